@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"rulehub/middleware"
+	"rulehub/models"
+	"rulehub/schemas"
+	"rulehub/utils"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const defaultAccessTokenDays = 30
+
+func accessTokenToResponse(token models.AccessToken) schemas.AccessTokenResponse {
+	return schemas.AccessTokenResponse{
+		ID:         token.ID.String(),
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     token.ScopeList(),
+		CreatedAt:  token.CreatedAt,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+	}
+}
+
+// AccessTokenCreateHandler issues a personal access token. The token is only
+// shown in this response.
+func (h *Handler) AccessTokenCreateHandler(c echo.Context) error {
+	req := c.Get("validatedBody").(*schemas.AccessTokenCreateRequest)
+
+	role := c.Get("userRole").(string)
+	for _, scope := range req.Scopes {
+		if !models.CanGrantScope(role, scope) {
+			log.Printf("User %v with role %v asked for scope %v", c.Get("userID"), role, scope)
+			return middleware.Forbidden(c)
+		}
+	}
+
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultAccessTokenDays
+	}
+
+	secret, prefix, hash, err := utils.GeneratePersonalToken()
+	if err != nil {
+		log.Printf("Error generating access token: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	token := models.AccessToken{
+		UserID:    c.Get("userID").(string),
+		Name:      req.Name,
+		Prefix:    prefix,
+		TokenHash: hash,
+		Scopes:    strings.Join(uniqueStrings(req.Scopes), " "),
+		ExpiresAt: time.Now().AddDate(0, 0, days),
+	}
+	if err := h.DB.Create(&token).Error; err != nil {
+		log.Printf("Error creating access token: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	return c.JSON(http.StatusCreated, schemas.AccessTokenCreateResponse{
+		AccessTokenResponse: accessTokenToResponse(token),
+		Token:               secret,
+	})
+}
+
+// AccessTokenListHandler lists the current user's usable tokens
+func (h *Handler) AccessTokenListHandler(c echo.Context) error {
+	var tokens []models.AccessToken
+	if err := h.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", c.Get("userID").(string), time.Now()).
+		Order("created_at DESC").Find(&tokens).Error; err != nil {
+		log.Printf("Error listing access tokens: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	resp := schemas.AccessTokenListResponse{Tokens: []schemas.AccessTokenResponse{}}
+	for _, token := range tokens {
+		resp.Tokens = append(resp.Tokens, accessTokenToResponse(token))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// AccessTokenRevokeHandler revokes one of the current user's tokens
+func (h *Handler) AccessTokenRevokeHandler(c echo.Context) error {
+	tokenID := c.Param("id")
+	if err := uuid.Validate(tokenID); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Invalid token id"})
+	}
+
+	result := h.DB.Model(&models.AccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, c.Get("userID").(string)).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		log.Printf("Error revoking access token: %v", result.Error)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	if result.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No such token"})
+	}
+
+	return c.JSON(http.StatusOK, schemas.Message{Status: "Token revoked"})
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
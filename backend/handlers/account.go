@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"rulehub/models"
+	"rulehub/schemas"
+	"rulehub/storage"
+	"rulehub/utils"
+	"rulehub/workers"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// UserExportHandler streams a zip with the data of the current user:
+// profile.json, every authored article (trashed ones included) as markdown
+// with front matter, the article media and the avatar
+func (h *Handler) UserExportHandler(c echo.Context) error {
+	var user models.User
+	if err := h.DB.Where("id = ?", c.Get("userID").(string)).First(&user).Error; err != nil {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "User not found"})
+	}
+
+	var articles []models.Article
+	if err := h.DB.Unscoped().Preload("Media", "deleted_at IS NULL").
+		Where("user_id = ?", user.ID).Order("created_at").Find(&articles).Error; err != nil {
+		log.Printf("Error loading articles for export: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "application/zip")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="rulehub-%s.zip"`, user.Username))
+	c.Response().WriteHeader(http.StatusOK)
+
+	// The status is sent already, on failure the client gets a broken archive
+	if err := h.writeExport(c.Request().Context(), c.Response(), user, articles); err != nil {
+		log.Printf("Error exporting data of %v: %v", user.Username, err)
+	}
+	return nil
+}
+
+func (h *Handler) writeExport(ctx context.Context, w io.Writer, user models.User, articles []models.Article) error {
+	archive := zip.NewWriter(w)
+
+	profile, err := json.MarshalIndent(h.userProfileResponse(user), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeZipFile(archive, "profile.json", profile); err != nil {
+		return err
+	}
+
+	if user.AvatarKey != "" {
+		if _, err := h.exportObject(ctx, archive, user.AvatarKey, path.Join("avatar", user.AvatarKey)); err != nil {
+			return err
+		}
+	}
+
+	// Articles may share objects, each one is written once
+	written := map[string]bool{}
+	for _, article := range articles {
+		var files []string
+		for _, media := range article.Media {
+			name := path.Join("media", media.S3Key, path.Base("/"+media.FileName))
+			if !written[name] {
+				found, err := h.exportObject(ctx, archive, media.S3Key, name)
+				if err != nil {
+					return err
+				}
+				if !found {
+					continue
+				}
+				written[name] = true
+			}
+			files = append(files, name)
+		}
+		if err := writeZipFile(archive, path.Join("articles", article.ID.String()+".md"), articleMarkdown(article, files)); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func writeZipFile(archive *zip.Writer, name string, data []byte) error {
+	f, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// exportObject copies an object into the archive. Objects missing from the
+// storage are skipped with a log line and reported as not found.
+func (h *Handler) exportObject(ctx context.Context, archive *zip.Writer, key, name string) (bool, error) {
+	object, info, err := h.Storage.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Export: object %v is missing, skipped", key)
+			return false, nil
+		}
+		return false, err
+	}
+	defer object.Close()
+
+	f, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: info.LastModified})
+	if err != nil {
+		return false, err
+	}
+	_, err = io.Copy(f, object)
+	return true, err
+}
+
+// articleMarkdown renders an article as markdown with YAML front matter.
+// JSON strings are valid YAML scalars, so they are used for quoting.
+func articleMarkdown(article models.Article, media []string) []byte {
+	quote := func(s string) string {
+		var b strings.Builder
+		encoder := json.NewEncoder(&b)
+		encoder.SetEscapeHTML(false)
+		encoder.Encode(s)
+		return strings.TrimSuffix(b.String(), "\n")
+	}
+
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "id: %s\n", article.ID)
+	fmt.Fprintf(&b, "title: %s\n", quote(article.Title))
+	fmt.Fprintf(&b, "created_at: %s\n", article.CreatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "updated_at: %s\n", article.UpdatedAt.UTC().Format(time.RFC3339))
+	if article.DeletedAt.Valid {
+		fmt.Fprintf(&b, "deleted_at: %s\n", article.DeletedAt.Time.UTC().Format(time.RFC3339))
+	}
+	if len(media) > 0 {
+		b.WriteString("media:\n")
+		for _, name := range media {
+			fmt.Fprintf(&b, "  - %s\n", quote(name))
+		}
+	}
+	b.WriteString("---\n\n")
+	b.WriteString(article.Content)
+	if !strings.HasSuffix(article.Content, "\n") {
+		b.WriteString("\n")
+	}
+	return []byte(b.String())
+}
+
+var errLastAdmin = errors.New("the last admin can not be deleted")
+
+// UserDeleteHandler deletes the account of the current user. Authored
+// articles, trashed ones included, are handed to another author or moved to
+// the trash; the user row is anonymised and every way to sign in is removed.
+func (h *Handler) UserDeleteHandler(c echo.Context) error {
+	req := c.Get("validatedBody").(*schemas.AccountDeleteRequest)
+
+	var user models.User
+	if err := h.DB.Where("id = ?", c.Get("userID").(string)).First(&user).Error; err != nil {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "User not found"})
+	}
+
+	// Accounts created through SSO have no password to confirm with
+	if user.Password != "" {
+		if wait := h.LoginThrottle.Check(user.Username, c.RealIP()); wait > 0 {
+			return tooManyAttempts(c, wait)
+		}
+		if !utils.CheckPassword(req.Password, user.Password) {
+			log.Printf("Invalid password on account deletion for user: %s", user.Username)
+			h.LoginThrottle.Failure(user.Username, c.RealIP())
+			return c.JSON(http.StatusForbidden, echo.Map{"message": "Invalid password"})
+		}
+	}
+
+	var heir models.User
+	if req.Articles == "reassign" {
+		if req.ReassignTo == "" {
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": "reassign_to is required"})
+		}
+		if err := h.DB.Where("username = ?", req.ReassignTo).First(&heir).Error; err != nil {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "No such user"})
+		}
+		if heir.ID == user.ID || !models.HasRole(heir.Role, models.RoleAuthor) {
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": "Articles can not be reassigned to this user"})
+		}
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if user.Role == models.RoleAdmin {
+			adminCount, err := models.LockAdmins(tx)
+			if err != nil {
+				return err
+			}
+			if adminCount <= 1 {
+				return errLastAdmin
+			}
+		}
+
+		var err error
+		if req.Articles == "reassign" {
+			err = tx.Unscoped().Model(&models.Article{}).Where("user_id = ?", user.ID).UpdateColumn("user_id", heir.ID).Error
+		} else {
+			err = tx.Where("user_id = ?", user.ID).Delete(&models.Article{}).Error
+		}
+		if err != nil {
+			return err
+		}
+		return anonymiseUser(tx, &user)
+	})
+	if errors.Is(err, errLastAdmin) {
+		return c.JSON(http.StatusConflict, echo.Map{"message": "Can not delete the last admin"})
+	}
+	if err != nil {
+		log.Printf("Error deleting account: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	log.Printf("User %v deleted their account, articles: %s", user.ID, req.Articles)
+
+	if user.AvatarKey != "" {
+		if _, err := workers.DemoteUnreferenced(h.DB, h.Storage, []string{user.AvatarKey}); err != nil {
+			log.Printf("Error reconciling avatar of deleted account: %v", err)
+		}
+	}
+
+	return c.JSON(http.StatusOK, schemas.Message{Status: "Account deleted"})
+}
+
+// anonymiseUser clears the personal data of the user row and soft-deletes it.
+// Sessions and tokens are revoked, identities and second factors removed. The
+// row itself stays so that revisions keep pointing at it; authors are loaded
+// with Unscoped wherever a deleted user can show up.
+func anonymiseUser(tx *gorm.DB, user *models.User) error {
+	userID := user.ID.String()
+
+	if err := revokeSessions(tx, "user_id = ?", userID); err != nil {
+		return err
+	}
+	if err := tx.Model(&models.AccessToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	for _, model := range []interface{}{&models.UserIdentity{}, &models.TwoFactor{}, &models.RecoveryCode{}, &models.PasswordReset{}} {
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+
+	err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"username":     "deleted_" + strings.ReplaceAll(userID, "-", "")[:24],
+		"password":     "",
+		"display_name": "",
+		"bio":          "",
+		"avatar_key":   "",
+		"role":         models.RoleReader,
+		"invite_id":    nil,
+	}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Where("id = ?", userID).Delete(&models.User{}).Error
+}
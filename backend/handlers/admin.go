@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"rulehub/models"
+	"rulehub/schemas"
+	"rulehub/workers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AdminSetUserRoleHandler changes the role of a user. The last admin can not
+// be demoted, so the hub always keeps someone able to manage it.
+func (h *Handler) AdminSetUserRoleHandler(c echo.Context) error {
+	roleData := c.Get("validatedBody").(*schemas.UserRoleUpdateRequest)
+	username := c.Param("username")
+
+	var user models.User
+	if err := h.DB.Where("username = ?", username).First(&user).Error; err != nil {
+		log.Printf("User not found: %v", username)
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No such user"})
+	}
+
+	if user.Role == models.RoleAdmin && roleData.Role != models.RoleAdmin {
+		var adminCount int64
+		if err := h.DB.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&adminCount).Error; err != nil {
+			log.Printf("Error counting admins: %v", err)
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+		}
+		if adminCount <= 1 {
+			return c.JSON(http.StatusConflict, echo.Map{"message": "Can not demote the last admin"})
+		}
+	}
+
+	if err := h.DB.Model(&user).Update("role", roleData.Role).Error; err != nil {
+		log.Printf("Error updating role: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	log.Printf("User %s is now %s", user.Username, roleData.Role)
+
+	return c.JSON(http.StatusOK, schemas.UserRoleResponse{
+		ID:       user.ID.String(),
+		Username: user.Username,
+		Role:     roleData.Role,
+	})
+}
+
+// AdminSweeperStatusHandler reports what the temporary upload sweeper has done
+func (h *Handler) AdminSweeperStatusHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.Sweeper.Status())
+}
+
+// AdminSweeperRunHandler runs a sweep right away and returns its counts
+func (h *Handler) AdminSweeperRunHandler(c echo.Context) error {
+	stats, err := h.Sweeper.SweepOnce(c.Request().Context())
+	if err != nil {
+		log.Printf("Error sweeping media: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	return c.JSON(http.StatusOK, stats)
+}
+
+// AdminMediaReconcileHandler diffs the bucket against the media table
+func (h *Handler) AdminMediaReconcileHandler(c echo.Context) error {
+	reconcileData := c.Get("validatedBody").(*schemas.MediaReconcileRequest)
+	mode := reconcileData.Mode
+	if mode == "" {
+		mode = "demote"
+	}
+
+	report, err := workers.ReconcileMedia(c.Request().Context(), h.DB, h.Storage, mode)
+	if err != nil {
+		log.Printf("Error reconciling media: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	return c.JSON(http.StatusOK, report)
+}
@@ -1,17 +1,21 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"path/filepath"
+	"rulehub/middleware"
 	"rulehub/models"
 	"rulehub/schemas"
-	"rulehub/utils"
+	"rulehub/workers"
 	"strings"
 
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	googleUUID "github.com/google/uuid"
 )
@@ -29,59 +33,41 @@ func (h *Handler) ArticleCreateHandler(c echo.Context) error {
 	article := models.Article{
 		Title:   article_data.Title,
 		Content: article_data.Content,
-		UserID:  c.Get("userID").(string),
-	}
-	if err := h.DB.Create(&article).Error; err != nil {
-		log.Printf("Error creating article: %v", err)
-		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+		UserID:  user.ID.String(),
+		User:    user,
 	}
 
-	var mediaResponses []schemas.MediaCreateResponse
-	for _, mediaPath := range article_data.Media {
-		// Extract the S3 key from the media path (which contains the temporary file location)
-		s3Key := extractS3KeyFromPath(mediaPath)
-
-		// Change file status from temporary to permanent
-		if err := utils.ChangeObjectStatusToPermanent(h.MinIOClient, os.Getenv("MINIO_BUCKET"), s3Key); err != nil {
-			if strings.Contains(err.Error(), "not found") {
-				log.Printf("Media not found in storage: %v", s3Key)
-				return c.JSON(http.StatusNotFound, echo.Map{"message": "Media not found"})
-			}
-			log.Printf("Error changing file status to permanent: %v", err)
-			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	// The article and its media are committed together. Tag changes can not
+	// be rolled back by the database, so the promoter reverts them on failure.
+	promoter := h.newObjectPromoter()
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(&article).Error; err != nil {
+			return err
 		}
-
-		// Get original filename
-		originalFileName := getOriginalFileName(mediaPath)
-
-		// Save the permanent file info in database
-		media := models.Media{
-			FileName:  originalFileName,
-			S3Key:     s3Key,
-			ArticleID: article.ID.String(),
+		if err := recordRevision(tx, &article, article.UserID, nil); err != nil {
+			return err
 		}
-		if err := h.DB.Create(&media).Error; err != nil {
-			log.Printf("Error creating media: %v", err)
-			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+		media, err := attachMedia(tx, promoter, article.UserID, article.ID.String(), article_data.Media, nil)
+		article.Media = media
+		return err
+	})
+	if err != nil {
+		promoter.revert()
+		if errors.Is(err, errMediaNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "Media not found"})
 		}
-
-		// Generate a permanent URL for the file
-		permanentURL := utils.GetPermanentObjectURL(os.Getenv("MINIO_BUCKET"), s3Key)
-
-		mediaResponses = append(mediaResponses, schemas.MediaCreateResponse{
-			FileName: originalFileName,
-			S3Key:    permanentURL, // Permanent URL for the file
-		})
+		if errors.Is(err, errMediaNotOwned) {
+			return c.JSON(http.StatusForbidden, echo.Map{"message": "Media was not uploaded by this user"})
+		}
+		if errors.Is(err, errMediaInvalid) {
+			return c.JSON(http.StatusUnprocessableEntity, echo.Map{"message": "Media is not a valid image"})
+		}
+		log.Printf("Error creating article: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
 	}
+	promoter.done()
 
-	resp := schemas.ArticleResponse{
-		ID:               article.ID.String(),
-		Title:            article.Title,
-		Content:          article.Content,
-		MediaPresignedUrl: mediaResponses,
-		AuthorUsername:   user.Username,
-	}
-	return c.JSON(http.StatusCreated, resp)
+	return c.JSON(http.StatusCreated, h.articleToResponse(article))
 }
 
 // extractS3KeyFromPath extracts the S3 key from a file path
@@ -130,22 +116,39 @@ func (h *Handler) ArticleGetHandler(c echo.Context) error {
 	}
 
 	var article models.Article
-	if err := h.DB.Preload("User").Preload("Media").Where("id = ?", uuid).First(&article).Error; err != nil {
+	if err := h.DB.Preload("User").Preload("Media.Variants").Where("id = ?", uuid).First(&article).Error; err != nil {
 		log.Printf("Error getting article with id: %v, 404", uuid)
 		return c.JSON(http.StatusNotFound, echo.Map{"message": "No such article"})
 	}
 
+	return c.JSON(http.StatusOK, h.articleToResponse(article))
+}
+
+// articleToResponse builds the API representation of an article with
+// preloaded User and Media
+func (h *Handler) articleToResponse(article models.Article) schemas.ArticleResponse {
 	var mediaResponses []schemas.MediaCreateResponse
-	bucketName := os.Getenv("MINIO_BUCKET")
 
 	for _, media := range article.Media {
 		// Generate permanent URL for each media file
-		permanentURL := utils.GetPermanentObjectURL(bucketName, media.S3Key)
+		permanentURL := h.Storage.PublicURL(media.S3Key)
 
-		mediaResponses = append(mediaResponses, schemas.MediaCreateResponse{
+		response := schemas.MediaCreateResponse{
 			FileName: media.FileName,
 			S3Key:    permanentURL,
-		})
+			MimeType: media.MimeType,
+			Size:     media.Size,
+			Width:    media.Width,
+			Height:   media.Height,
+			SHA256:   media.SHA256,
+		}
+		if len(media.Variants) > 0 {
+			response.Variants = make(map[string]string, len(media.Variants))
+			for _, variant := range media.Variants {
+				response.Variants[fmt.Sprintf("%s_%d", variant.Format, variant.Width)] = h.Storage.PublicURL(variant.Key)
+			}
+		}
+		mediaResponses = append(mediaResponses, response)
 	}
 
 	resp := schemas.ArticleResponse{
@@ -154,8 +157,14 @@ func (h *Handler) ArticleGetHandler(c echo.Context) error {
 		Content:          article.Content,
 		MediaPresignedUrl: mediaResponses,
 		AuthorUsername:   article.User.Username,
+		AuthorProfile:    h.authorSummary(article.User),
+		CreatedAt:        article.CreatedAt,
+		UpdatedAt:        article.UpdatedAt,
+	}
+	if article.DeletedAt.Valid {
+		resp.DeletedAt = &article.DeletedAt.Time
 	}
-	return c.JSON(http.StatusOK, resp)
+	return resp
 }
 
 func (h *Handler) ArticleChangeHandler(c echo.Context) error {
@@ -167,94 +176,78 @@ func (h *Handler) ArticleChangeHandler(c echo.Context) error {
 	}
 
 	var article models.Article
-	if err := h.DB.Preload("Media").Where("id = ?", uuid).First(&article).Error; err != nil {
+	if err := h.DB.Preload("User").Preload("Media.Variants").Where("id = ?", uuid).First(&article).Error; err != nil {
 		log.Printf("Article not found: %v", uuid)
 		return c.JSON(http.StatusNotFound, echo.Map{"message": "No such article"})
 	}
 
+	if !models.CanModifyArticle(c.Get("userID").(string), c.Get("userRole").(string), &article) {
+		log.Printf("User %v is not allowed to update article %v", c.Get("userID"), uuid)
+		return middleware.Forbidden(c)
+	}
+
 	articleData := c.Get("validatedBody").(*schemas.ArticleUpdateRequest)
 	log.Printf("Updating article: %+v", articleData)
 
+	oldTitle, oldContent := article.Title, article.Content
 	if articleData.Title != nil {
 		article.Title = *articleData.Title
 	}
 	if articleData.Content != nil {
 		article.Content = *articleData.Content
 	}
+	textChanged := article.Title != oldTitle || article.Content != oldContent
 
-	var mediaResponses []schemas.MediaCreateResponse
-
-	if articleData.Media != nil {
-		// Delete old media from database
-		if err := h.DB.Where("article_id = ?", article.ID).Delete(&models.Media{}).Error; err != nil {
-			log.Printf("Error deleting old media: %v", err)
-			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
-		}
-		article.Media = nil
-
-		// Process new media files that are already uploaded as temporary
-		for _, mediaPath := range *articleData.Media {
-			// Extract the S3 key from the media path
-			s3Key := extractS3KeyFromPath(mediaPath)
-
-			// Change file status from temporary to permanent
-			if err := utils.ChangeObjectStatusToPermanent(h.MinIOClient, os.Getenv("MINIO_BUCKET"), s3Key); err != nil {
-				log.Printf("Error changing file status to permanent: %v", err)
-				return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	var replacedKeys []string
+	promoter := h.newObjectPromoter()
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if articleData.Media != nil {
+			for _, media := range article.Media {
+				replacedKeys = append(replacedKeys, media.S3Key)
 			}
 
-			// Get original filename
-			originalFileName := getOriginalFileName(mediaPath)
-
-			// Save the permanent file info in database
-			media := models.Media{
-				FileName:  originalFileName,
-				S3Key:     s3Key,
-				ArticleID: article.ID.String(),
-			}
-			if err := h.DB.Create(&media).Error; err != nil {
-				log.Printf("Error creating media: %v", err)
-				return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+			// Delete old media from database
+			if err := tx.Where("article_id = ?", article.ID).Delete(&models.Media{}).Error; err != nil {
+				return err
 			}
-			article.Media = append(article.Media, media)
 
-			// Generate a permanent URL for the file
-			permanentURL := utils.GetPermanentObjectURL(os.Getenv("MINIO_BUCKET"), s3Key)
+			// Process new media files that are already uploaded as temporary
+			media, err := attachMedia(tx, promoter, c.Get("userID").(string), article.ID.String(), *articleData.Media, replacedKeys)
+			if err != nil {
+				return err
+			}
+			article.Media = media
+		}
 
-			mediaResponses = append(mediaResponses, schemas.MediaCreateResponse{
-				FileName: originalFileName,
-				S3Key:    permanentURL, // Permanent URL for the file
-			})
+		if err := tx.Omit(clause.Associations).Save(&article).Error; err != nil {
+			return err
 		}
-	} else {
-		// If media was not updated, return permanent URLs for existing files
-		for _, media := range article.Media {
-			permanentURL := utils.GetPermanentObjectURL(os.Getenv("MINIO_BUCKET"), media.S3Key)
-
-			mediaResponses = append(mediaResponses, schemas.MediaCreateResponse{
-				FileName: media.FileName,
-				S3Key:    permanentURL, // Permanent URL for the file
-			})
+		if textChanged {
+			return recordRevision(tx, &article, c.Get("userID").(string), nil)
+		}
+		return nil
+	})
+	if err != nil {
+		promoter.revert()
+		if errors.Is(err, errMediaNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "Media not found"})
+		}
+		if errors.Is(err, errMediaNotOwned) {
+			return c.JSON(http.StatusForbidden, echo.Map{"message": "Media was not uploaded by this user"})
+		}
+		if errors.Is(err, errMediaInvalid) {
+			return c.JSON(http.StatusUnprocessableEntity, echo.Map{"message": "Media is not a valid image"})
 		}
-	}
-
-	if err := h.DB.Save(&article).Error; err != nil {
 		log.Printf("Error updating article: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
 	}
+	promoter.done()
 
-	var user models.User
-	if err := h.DB.Where("id = ?", article.UserID).First(&user).Error; err != nil {
-		log.Printf("User not found for article: %v", article.UserID)
-		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	// Objects that were dropped from the article and are not used elsewhere
+	// are handed over to the sweeper
+	if _, err := workers.DemoteUnreferenced(h.DB, h.Storage, replacedKeys); err != nil {
+		log.Printf("Error reconciling replaced media: %v", err)
 	}
 
-	resp := schemas.ArticleResponse{
-		ID:               article.ID.String(),
-		Title:            article.Title,
-		Content:          article.Content,
-		MediaPresignedUrl: mediaResponses,
-		AuthorUsername:   user.Username,
-	}
-	return c.JSON(http.StatusOK, resp)
-}
\ No newline at end of file
+	return c.JSON(http.StatusOK, h.articleToResponse(article))
+}
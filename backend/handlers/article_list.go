@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"rulehub/models"
+	"rulehub/schemas"
+	"rulehub/utils"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const defaultArticlePageSize = 20
+
+// articleSortColumns maps the public sort names to article columns
+var articleSortColumns = map[string]string{
+	"created": "articles.created_at",
+	"updated": "articles.updated_at",
+	"title":   "articles.title",
+}
+
+// invalidQueryError is a list query the client has to fix
+type invalidQueryError struct {
+	message string
+}
+
+func (e invalidQueryError) Error() string { return e.message }
+
+// ArticleListHandler returns a page of articles ordered by the requested key.
+// Pagination is keyset based: next_cursor points past the last returned row.
+func (h *Handler) ArticleListHandler(c echo.Context) error {
+	query := c.Get("validatedBody").(*schemas.ArticleListQuery)
+
+	resp, err := h.listArticles(query)
+	var invalid invalidQueryError
+	if errors.As(err, &invalid) {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": invalid.message})
+	}
+	if err != nil {
+		log.Printf("Error listing articles: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// listArticles loads one page of the articles matching query
+func (h *Handler) listArticles(query *schemas.ArticleListQuery) (schemas.ArticleListResponse, error) {
+	resp := schemas.ArticleListResponse{Articles: []schemas.ArticleResponse{}}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultArticlePageSize
+	}
+	sort := query.Sort
+	if sort == "" {
+		sort = "created"
+	}
+	order := query.Order
+	if order == "" {
+		order = "desc"
+	}
+
+	db := h.DB.Model(&models.Article{})
+
+	if query.Author != "" {
+		db = db.Joins("JOIN users ON users.id = articles.user_id").Where("users.username = ?", query.Author)
+	}
+	if query.From != "" {
+		from, err := time.Parse(time.RFC3339, query.From)
+		if err != nil {
+			return resp, invalidQueryError{"Invalid from date, RFC3339 expected"}
+		}
+		db = db.Where("articles.created_at >= ?", from)
+	}
+	if query.To != "" {
+		to, err := time.Parse(time.RFC3339, query.To)
+		if err != nil {
+			return resp, invalidQueryError{"Invalid to date, RFC3339 expected"}
+		}
+		db = db.Where("articles.created_at < ?", to)
+	}
+
+	if query.Cursor != "" {
+		cursor, err := utils.DecodeCursor(query.Cursor)
+		if err != nil || cursor.Sort != sort || cursor.Order != order {
+			return resp, invalidQueryError{"Invalid cursor"}
+		}
+		db, err = applyArticleCursor(db, sort, order, cursor)
+		if err != nil {
+			return resp, invalidQueryError{"Invalid cursor"}
+		}
+	}
+
+	column := articleSortColumns[sort]
+	var articles []models.Article
+	if err := db.Preload("User").Preload("Media.Variants").
+		Order(column + " " + order).Order("articles.id " + order).
+		Limit(limit + 1).Find(&articles).Error; err != nil {
+		return resp, err
+	}
+
+	if len(articles) > limit {
+		articles = articles[:limit]
+		resp.NextCursor = utils.EncodeCursor(articleCursor(articles[len(articles)-1], sort, order))
+	}
+	for _, article := range articles {
+		resp.Articles = append(resp.Articles, h.articleToResponse(article))
+	}
+	return resp, nil
+}
+
+// articleCursor captures the position of an article in the given ordering
+func articleCursor(article models.Article, sort, order string) utils.Cursor {
+	cursor := utils.Cursor{Sort: sort, Order: order, ID: article.ID.String()}
+	switch sort {
+	case "created":
+		cursor.Value = article.CreatedAt.Format(time.RFC3339Nano)
+	case "updated":
+		cursor.Value = article.UpdatedAt.Format(time.RFC3339Nano)
+	case "title":
+		cursor.Value = article.Title
+	}
+	return cursor
+}
+
+// applyArticleCursor restricts the query to rows strictly after the cursor
+func applyArticleCursor(db *gorm.DB, sort, order string, cursor utils.Cursor) (*gorm.DB, error) {
+	if err := uuid.Validate(cursor.ID); err != nil {
+		return nil, err
+	}
+
+	op := "<"
+	if order == "asc" {
+		op = ">"
+	}
+
+	var value interface{} = cursor.Value
+	if sort != "title" {
+		t, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, err
+		}
+		value = t
+	}
+
+	return db.Where("("+articleSortColumns[sort]+", articles.id) "+op+" (?, ?)", value, cursor.ID), nil
+}
+
+// headlineOptions control ts_headline output; matches are wrapped in <mark>
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10, FragmentDelimiter=\" ... \""
+
+// ArticleSearchHandler runs a ranked full-text search over titles and content
+func (h *Handler) ArticleSearchHandler(c echo.Context) error {
+	query := c.Get("validatedBody").(*schemas.ArticleSearchQuery)
+
+	tsQuery, err := utils.BuildTSQuery(query.Q)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Search query has no words"})
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultArticlePageSize
+	}
+
+	results := []schemas.ArticleSearchResult{}
+	if err := h.DB.Raw(`
+		SELECT articles.id, articles.title, users.username AS author_username,
+			articles.created_at, articles.updated_at,
+			ts_rank_cd(articles.search_vector, q) AS rank,
+			ts_headline(?::regconfig, articles.title, q, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS title_highlight,
+			ts_headline(?::regconfig, articles.content, q, ?) AS snippet
+		FROM articles
+		JOIN users ON users.id = articles.user_id,
+			to_tsquery(?::regconfig, ?) AS q
+		WHERE articles.deleted_at IS NULL AND articles.search_vector @@ q
+		ORDER BY rank DESC, articles.id
+		LIMIT ? OFFSET ?`,
+		models.SearchConfig, models.SearchConfig, headlineOptions,
+		models.SearchConfig, tsQuery, limit, query.Offset,
+	).Scan(&results).Error; err != nil {
+		log.Printf("Error searching articles: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, schemas.ArticleSearchResponse{Results: results})
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"rulehub/middleware"
+	"rulehub/models"
+	"rulehub/schemas"
+	"rulehub/workers"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	googleUUID "github.com/google/uuid"
+)
+
+// ArticleDeleteHandler moves an article to its author's trash
+func (h *Handler) ArticleDeleteHandler(c echo.Context) error {
+	uuid := c.Param("uuid")
+	if err := googleUUID.Validate(uuid); err != nil {
+		log.Printf("Error deleting article, bad uuid: %v", uuid)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "UUID is required"})
+	}
+
+	var article models.Article
+	if err := h.DB.Where("id = ?", uuid).First(&article).Error; err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No such article"})
+	}
+
+	if !models.CanModifyArticle(c.Get("userID").(string), c.Get("userRole").(string), &article) {
+		log.Printf("User %v is not allowed to delete article %v", c.Get("userID"), uuid)
+		return middleware.Forbidden(c)
+	}
+
+	if err := h.DB.Delete(&article).Error; err != nil {
+		log.Printf("Error deleting article: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	log.Printf("Article %v moved to trash", uuid)
+
+	return c.JSON(http.StatusOK, schemas.Message{Status: "Article moved to trash"})
+}
+
+// ArticleTrashListHandler lists the caller's soft-deleted articles
+func (h *Handler) ArticleTrashListHandler(c echo.Context) error {
+	var articles []models.Article
+	if err := h.DB.Unscoped().Preload("User").Preload("Media", "deleted_at IS NULL").Preload("Media.Variants").
+		Where("user_id = ? AND deleted_at IS NOT NULL", c.Get("userID").(string)).
+		Order("deleted_at DESC").Find(&articles).Error; err != nil {
+		log.Printf("Error listing trash: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	resp := schemas.ArticleListResponse{Articles: []schemas.ArticleResponse{}}
+	for _, article := range articles {
+		resp.Articles = append(resp.Articles, h.articleToResponse(article))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// findTrashedArticle loads a soft-deleted article and checks that the caller
+// may manage it. When it returns nil the error response is already written.
+func (h *Handler) findTrashedArticle(c echo.Context) (*models.Article, error) {
+	uuid := c.Param("uuid")
+	if err := googleUUID.Validate(uuid); err != nil {
+		return nil, c.JSON(http.StatusBadRequest, echo.Map{"message": "UUID is required"})
+	}
+
+	var article models.Article
+	if err := h.DB.Unscoped().Preload("User").Where("id = ? AND deleted_at IS NOT NULL", uuid).First(&article).Error; err != nil {
+		return nil, c.JSON(http.StatusNotFound, echo.Map{"message": "No such article in trash"})
+	}
+
+	if !models.CanModifyArticle(c.Get("userID").(string), c.Get("userRole").(string), &article) {
+		log.Printf("User %v is not allowed to manage trashed article %v", c.Get("userID"), uuid)
+		return nil, middleware.Forbidden(c)
+	}
+	return &article, nil
+}
+
+// ArticleRestoreHandler takes an article back out of the trash
+func (h *Handler) ArticleRestoreHandler(c echo.Context) error {
+	article, err := h.findTrashedArticle(c)
+	if article == nil {
+		return err
+	}
+
+	if err := h.DB.Unscoped().Model(article).Update("deleted_at", nil).Error; err != nil {
+		log.Printf("Error restoring article: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	article.DeletedAt = gorm.DeletedAt{}
+
+	if err := h.DB.Preload("Variants").Where("article_id = ?", article.ID).Find(&article.Media).Error; err != nil {
+		log.Printf("Error loading media: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, h.articleToResponse(*article))
+}
+
+// ArticlePurgeHandler permanently removes a trashed article together with its
+// revisions and media rows. Media objects are deleted unless another article
+// or a profile shares them.
+func (h *Handler) ArticlePurgeHandler(c echo.Context) error {
+	article, err := h.findTrashedArticle(c)
+	if article == nil {
+		return err
+	}
+
+	var media []models.Media
+	if err := h.DB.Unscoped().Where("article_id = ?", article.ID).Find(&media).Error; err != nil {
+		log.Printf("Error loading media: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("article_id = ?", article.ID).Delete(&models.Media{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("article_id = ?", article.ID).Delete(&models.ArticleRevision{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(article).Error
+	})
+	if err != nil {
+		log.Printf("Error purging article: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	// With the rows gone only objects that nothing else shares are deleted.
+	// Objects left behind by a storage failure are found by reconciliation.
+	keys := make([]string, 0, len(media))
+	for _, m := range media {
+		keys = append(keys, m.S3Key)
+	}
+	deleted, err := workers.DeleteUnreferenced(h.DB, h.Storage, keys)
+	if err != nil {
+		log.Printf("Error deleting media objects of purged article %v: %v", article.ID, err)
+	}
+	log.Printf("Article %v purged with %d media files, %d objects deleted", article.ID, len(media), len(deleted))
+
+	return c.JSON(http.StatusOK, schemas.Message{Status: "Article purged"})
+}
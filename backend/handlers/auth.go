@@ -1,54 +1,81 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"log"
-	"os"
+	"time"
 
 	"rulehub/models"
 	"rulehub/schemas"
 	"rulehub/utils"
 
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
 func (h* Handler) UserLoginHandler(c echo.Context) error {
 	user_data := c.Get("validatedBody").(*schemas.SignInRequest)
-	log.Printf("Logining user: %+v", user_data)
+	log.Printf("Logining user: %v", user_data)
+
+	// Locked usernames and IPs are refused before the password is even checked
+	if wait := h.LoginThrottle.Check(user_data.Username, c.RealIP()); wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
 
 	// Check if the user exists in the database and validate the password
 	var user models.User
 	if err := h.DB.Where("username = ?", user_data.Username).First(&user).Error; err != nil {
 		log.Printf("User not found: %v", err)
+		h.LoginThrottle.Failure(user_data.Username, c.RealIP())
 		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "Invalid username or password"})
 	}
 
 	if utils.CheckPassword(user_data.Password, user.Password) == false {
 		log.Printf("Invalid password for user: %s", user_data.Username)
+		h.LoginThrottle.Failure(user_data.Username, c.RealIP())
 		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "Invalid username or password"})
 	}
 
-	// Generate access and refresh tokens
-	accessToken, err := utils.GenerateAccessToken(user.ID.String(), user.Username)
+	// Move legacy and outdated hashes to the current scheme while we know the password
+	if utils.NeedsRehash(user.Password) {
+		if passwordHash, err := utils.HashPassword(user_data.Password); err != nil {
+			log.Printf("Error rehashing password: %v", err)
+		} else if err := h.DB.Model(&user).Update("password", passwordHash).Error; err != nil {
+			log.Printf("Error saving rehashed password: %v", err)
+		}
+	}
+
+	// With 2FA enabled the password only earns a challenge for the second step
+	twoFactor, err := findTwoFactor(h.DB, user.ID.String())
 	if err != nil {
-		log.Printf("Error generating access token: %v", err)
+		log.Printf("Error loading two-factor settings: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
 	}
-	refreshToken, err := utils.GenerateRefreshToken(user.ID.String())
+	if twoFactor != nil && twoFactor.Enabled() {
+		challenge, err := utils.GenerateChallengeToken(user.ID.String())
+		if err != nil {
+			log.Printf("Error generating challenge token: %v", err)
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+		}
+		return c.JSON(http.StatusOK, schemas.TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+		})
+	}
+
+	h.LoginThrottle.Success(user.Username)
+
+	// Start a session: refresh token goes to an HttpOnly cookie
+	accessToken, err := h.startSession(c, user)
 	if err != nil {
-		log.Printf("Error generating refresh token: %v", err)
+		log.Printf("Error starting session: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
 	}
 
-	// Set the refresh token in an HttpOnly cookie
-	cookie := new(http.Cookie)
-	cookie.Name = "refresh_token"
-	cookie.Value = refreshToken
-	cookie.HttpOnly = true
-	cookie.Path = "/"
-	c.SetCookie(cookie)
-
 	return c.JSON(http.StatusOK, schemas.SignInResponse{
 		AccessJWT: accessToken,
 	})
@@ -56,7 +83,7 @@ func (h* Handler) UserLoginHandler(c echo.Context) error {
 
 func (h* Handler) UserRegistrationHandler(c echo.Context) error {
 	user_data := c.Get("validatedBody").(*schemas.SignUpRequest)
-	log.Printf("Registering user: %+v", user_data)
+	log.Printf("Registering user: %v", user_data)
 
 	// Check if the username already exists
 	var existingUser models.User
@@ -65,13 +92,58 @@ func (h* Handler) UserRegistrationHandler(c echo.Context) error {
 		return c.JSON(http.StatusConflict, echo.Map{"message": "Username already exists"})
 	}
 
+	passwordHash, err := utils.HashPassword(user_data.Password)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
 	// Create a new user
 	newUser := models.User{
 		Username: user_data.Username,
-		Password: utils.HashPassword(user_data.Password),
+		Password: passwordHash,
+		Role:     models.RoleAuthor,
 	}
 
-	if err := h.DB.Create(&newUser).Error; err != nil {
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		// The very first account administers the hub, whatever the registration mode
+		first, err := models.LockFirstUser(tx)
+		if err != nil {
+			return err
+		}
+		switch mode := models.RegistrationMode(); {
+		case first:
+			newUser.Role = models.RoleAdmin
+			return tx.Create(&newUser).Error
+		case mode == models.RegistrationClosed:
+			return errRegistrationClosed
+		case mode == models.RegistrationInvite && user_data.InviteCode == "":
+			return errInviteRequired
+		}
+
+		// An invite decides the role of everyone but the first account
+		if user_data.InviteCode != "" {
+			invite, err := redeemInvite(tx, user_data.InviteCode)
+			if err != nil {
+				return err
+			}
+			inviteID := invite.ID.String()
+			newUser.Role = invite.Role
+			newUser.InviteID = &inviteID
+		}
+		return tx.Create(&newUser).Error
+	})
+	if errors.Is(err, errRegistrationClosed) {
+		return c.JSON(http.StatusForbidden, echo.Map{"message": "Registration is closed"})
+	}
+	if errors.Is(err, errInviteRequired) {
+		return c.JSON(http.StatusForbidden, echo.Map{"message": "Registration requires an invite"})
+	}
+	if errors.Is(err, errInvalidInvite) {
+		log.Printf("Invalid invite used to register: %s", user_data.Username)
+		return c.JSON(http.StatusForbidden, echo.Map{"message": "Invalid or expired invite"})
+	}
+	if err != nil {
 		log.Printf("Error creating user: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
 	}
@@ -82,33 +154,85 @@ func (h* Handler) UserRegistrationHandler(c echo.Context) error {
 	})
 }
 
+// UserJwtRefreshHandler exchanges a refresh token for a new access token and
+// rotates the refresh token. Presenting a token that was already rotated means
+// it leaked, so the whole session is revoked.
 func (h* Handler) UserJwtRefreshHandler(c echo.Context) error {
-	refreshToken, err := c.Cookie("refresh_token")
+	refreshToken, err := c.Cookie(refreshCookieName)
 	if err != nil {
 		log.Printf("No refresh token found: %v", err)
 		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "Refresh token not found"})
 	}
 
-	jwtClaims, err := utils.ValidateToken(refreshToken.Value, []byte(os.Getenv("PASSWORD_JWT_REFRESH_SECRET")))
+	jwtClaims, err := utils.ValidateToken(refreshToken.Value, utils.RefreshKeyring())
 	if err != nil {
 		log.Printf("Invalid refresh token: %v", err)
 		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "Invalid refresh token"})
 	}
 
-	userID, ok := jwtClaims["user_id"].(string)
+	jti, ok := jwtClaims["jti"].(string)
 	if !ok {
-		log.Println("Invalid user ID in token claims")
+		log.Println("Invalid jti in token claims")
 		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "Invalid token claims"})
 	}
 
+	var token models.RefreshToken
+	if err := h.DB.Preload("Session").Where("jti = ?", jti).First(&token).Error; err != nil {
+		log.Printf("Unknown refresh token: %v", jti)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "Invalid refresh token"})
+	}
+	session := token.Session
+
+	if !session.Active() {
+		clearRefreshCookie(c)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "Session expired or revoked"})
+	}
+
+	if token.RotatedAt != nil && time.Since(*token.RotatedAt) > refreshReuseGrace {
+		log.Printf("Refresh token reuse detected, revoking session %v", session.ID)
+		if err := revokeSessions(h.DB, "id = ?", session.ID); err != nil {
+			log.Printf("Error revoking session: %v", err)
+		}
+		clearRefreshCookie(c)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "Refresh token reuse detected"})
+	}
+
 	// Check if the user exists in the database
 	var user models.User
-	if err := h.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+	if err := h.DB.Where("id = ?", session.UserID).First(&user).Error; err != nil {
 		log.Printf("User not found: %v", err)
 		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "User not found"})
 	}
+
+	// Rotate: the presented token is spent, the session slides forward
+	now := time.Now()
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(utils.RefreshTokenLifetime)
+	session.IP = truncate(c.RealIP(), 64)
+	session.UserAgent = truncate(c.Request().UserAgent(), 256)
+
+	var newRefreshToken string
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if token.RotatedAt == nil {
+			if err := tx.Model(&token).Update("rotated_at", now).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&session).Select("last_used_at", "expires_at", "ip", "user_agent").Updates(&session).Error; err != nil {
+			return err
+		}
+		var err error
+		newRefreshToken, err = issueRefreshToken(tx, &session)
+		return err
+	})
+	if err != nil {
+		log.Printf("Error rotating refresh token: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	setRefreshCookie(c, newRefreshToken, session.ExpiresAt)
+
 	// Generate a new access token
-	accessToken, err := utils.GenerateAccessToken(user.ID.String(), user.Username)
+	accessToken, err := utils.GenerateAccessToken(user.ID.String(), user.Username, user.Role, session.ID.String())
 	if err != nil {
 		log.Printf("Error generating access token: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
@@ -117,4 +241,47 @@ func (h* Handler) UserJwtRefreshHandler(c echo.Context) error {
 	return c.JSON(http.StatusOK, schemas.SignInResponse{
 		AccessJWT: accessToken,
 	})
-}
\ No newline at end of file
+}
+
+// UserLogoutHandler ends the session of the refresh cookie. It always clears
+// the cookie, even if the token is already invalid.
+func (h* Handler) UserLogoutHandler(c echo.Context) error {
+	clearRefreshCookie(c)
+
+	refreshToken, err := c.Cookie(refreshCookieName)
+	if err != nil {
+		return c.JSON(http.StatusOK, schemas.Message{Status: "Logged out"})
+	}
+	jwtClaims, err := utils.ValidateToken(refreshToken.Value, utils.RefreshKeyring())
+	if err != nil {
+		return c.JSON(http.StatusOK, schemas.Message{Status: "Logged out"})
+	}
+	jti, _ := jwtClaims["jti"].(string)
+
+	var token models.RefreshToken
+	if err := h.DB.Where("jti = ?", jti).First(&token).Error; err == nil {
+		if err := revokeSessions(h.DB, "id = ?", token.SessionID); err != nil {
+			log.Printf("Error revoking session: %v", err)
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+		}
+	}
+
+	return c.JSON(http.StatusOK, schemas.Message{Status: "Logged out"})
+}
+
+// UserLogoutAllHandler revokes every session of the current user
+func (h* Handler) UserLogoutAllHandler(c echo.Context) error {
+	if err := revokeSessions(h.DB, "user_id = ?", c.Get("userID").(string)); err != nil {
+		log.Printf("Error revoking sessions: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	clearRefreshCookie(c)
+
+	return c.JSON(http.StatusOK, schemas.Message{Status: "Logged out everywhere"})
+}
+
+// tooManyAttempts answers a login attempt of a locked username or IP
+func tooManyAttempts(c echo.Context, wait time.Duration) error {
+	c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	return c.JSON(http.StatusTooManyRequests, echo.Map{"message": "Too many failed attempts, try again later"})
+}
@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"net/http"
+	"rulehub/models"
 	"rulehub/schemas"
+	"rulehub/utils"
+	"strings"
 
 	"log"
 
@@ -43,8 +46,45 @@ func (h* Handler) DropDB(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
 	}
 
+	// Failed logins of removed users should not lock out the next test run
+	h.LoginThrottle.Reset()
+
 	log.Println("Database reset successfully")
 	return c.JSON(http.StatusOK, schemas.Message{
 		Status: "Database reset successfully",
 	})
-}
\ No newline at end of file
+}
+// DevSetLegacyPassword stores a password of a user in the legacy
+// PASSWORD_SALT format, so tests can check the upgrade on login
+func (h* Handler) DevSetLegacyPassword(c echo.Context) error {
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := c.Bind(&req); err != nil || req.Password == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Password is required"})
+	}
+	result := h.DB.Model(&models.User{}).Where("username = ?", c.Param("username")).
+		Update("password", utils.LegacyHashPassword(req.Password))
+	if result.Error != nil {
+		log.Printf("Error setting legacy password: %v", result.Error)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	if result.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "User not found"})
+	}
+	return c.JSON(http.StatusOK, schemas.Message{Status: "Legacy password set"})
+}
+
+// DevPasswordFormat reports how the password of a user is stored: "phc" or
+// "legacy"
+func (h* Handler) DevPasswordFormat(c echo.Context) error {
+	var user models.User
+	if err := h.DB.Where("username = ?", c.Param("username")).First(&user).Error; err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "User not found"})
+	}
+	format := "phc"
+	if !strings.HasPrefix(user.Password, "$") {
+		format = "legacy"
+	}
+	return c.JSON(http.StatusOK, echo.Map{"format": format})
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"rulehub/storage"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxUploadField limits the size of the text fields of an upload form
+const maxUploadField = 4 << 10
+
+// inlineTypes can be shown in the browser, everything else is downloaded
+var inlineTypes = map[string]bool{"image/jpeg": true, "image/png": true, "image/gif": true, "image/webp": true}
+
+// FileGetHandler serves an object of the local storage
+func (h *Handler) FileGetHandler(c echo.Context) error {
+	local, ok := h.Storage.(*storage.Local)
+	if !ok {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "File not found"})
+	}
+
+	f, info, err := local.Open(c.Param("*"))
+	if errors.Is(err, storage.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "File not found"})
+	}
+	if err != nil {
+		log.Printf("Error opening file: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	defer f.Close()
+
+	// Files are served from the API origin with the type declared by the
+	// uploader, so they must not run scripts there
+	header := c.Response().Header()
+	if info.ContentType != "" {
+		header.Set(echo.HeaderContentType, info.ContentType)
+	}
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set(echo.HeaderContentSecurityPolicy, "default-src 'none'; sandbox")
+	if mediaType, _, _ := mime.ParseMediaType(info.ContentType); !inlineTypes[mediaType] {
+		header.Set(echo.HeaderContentDisposition, "attachment")
+	}
+	http.ServeContent(c.Response(), c.Request(), "", info.LastModified, f)
+	return nil
+}
+
+// FileUploadHandler accepts a browser POST upload issued by
+// /media/upload-temp. Like an S3 POST policy, the form fields come first
+// and the file is the last part; the fields are checked before the file is
+// read.
+func (h *Handler) FileUploadHandler(c echo.Context) error {
+	local, ok := h.Storage.(*storage.Local)
+	if !ok {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "File not found"})
+	}
+
+	reader, err := c.Request().MultipartReader()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Invalid upload form"})
+	}
+	fields := map[string]string{}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": "Invalid upload form"})
+		}
+		if part.FormName() != "file" {
+			value, err := io.ReadAll(io.LimitReader(part, maxUploadField))
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, echo.Map{"message": "Invalid upload form"})
+			}
+			fields[part.FormName()] = string(value)
+			continue
+		}
+
+		err = local.Receive(fields, part)
+		switch {
+		case err == nil:
+			return c.NoContent(http.StatusNoContent)
+		case errors.Is(err, storage.ErrInvalidUpload), errors.Is(err, storage.ErrNotFound):
+			return c.JSON(http.StatusForbidden, echo.Map{"message": "Upload policy rejected"})
+		case errors.Is(err, storage.ErrUploadSize):
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": "File size out of range"})
+		default:
+			log.Printf("Error storing upload: %v", err)
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+		}
+	}
+}
@@ -1,11 +1,18 @@
 package handlers
 
 import (
-	"github.com/minio/minio-go/v7"
+	"rulehub/storage"
+	"rulehub/utils"
+	"rulehub/workers"
+
 	"gorm.io/gorm"
 )
 
 type Handler struct {
 	DB *gorm.DB
-	MinIOClient *minio.Client
+	Storage storage.Storage
+	Sweeper *workers.MediaSweeper
+	Variants *workers.VariantGenerator
+	LoginThrottle *utils.LoginThrottle
+	OIDC *utils.OIDCProvider // nil when OIDC login is not configured
 }
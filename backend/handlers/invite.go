@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"rulehub/models"
+	"rulehub/schemas"
+	"rulehub/utils"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultInviteMaxUses = 1
+	defaultInviteHours   = 7 * 24
+)
+
+var (
+	errInvalidInvite      = errors.New("invalid or expired invite")
+	errRegistrationClosed = errors.New("registration is closed")
+	errInviteRequired     = errors.New("registration requires an invite")
+)
+
+// redeemInvite counts one use of an invite. The row is locked, so parallel
+// registrations can not use a single-use code twice.
+func redeemInvite(tx *gorm.DB, code string) (*models.Invite, error) {
+	var invite models.Invite
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("code_hash = ?", utils.HashInviteCode(code)).First(&invite).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errInvalidInvite
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !invite.Usable() {
+		return nil, errInvalidInvite
+	}
+
+	if err := tx.Model(&invite).UpdateColumn("uses", gorm.Expr("uses + 1")).Error; err != nil {
+		return nil, err
+	}
+	invite.Uses++
+	return &invite, nil
+}
+
+func inviteToResponse(invite models.Invite) schemas.InviteResponse {
+	return schemas.InviteResponse{
+		ID:        invite.ID.String(),
+		Prefix:    invite.Prefix,
+		Role:      invite.Role,
+		MaxUses:   invite.MaxUses,
+		Uses:      invite.Uses,
+		CreatedBy: invite.CreatedBy.Username,
+		CreatedAt: invite.CreatedAt,
+		ExpiresAt: invite.ExpiresAt,
+		RevokedAt: invite.RevokedAt,
+	}
+}
+
+// AdminInviteCreateHandler issues an invite code. The code is only shown in
+// this response.
+func (h *Handler) AdminInviteCreateHandler(c echo.Context) error {
+	req := c.Get("validatedBody").(*schemas.InviteCreateRequest)
+
+	maxUses := req.MaxUses
+	if maxUses == 0 {
+		maxUses = defaultInviteMaxUses
+	}
+	hours := req.ExpiresInHours
+	if hours == 0 {
+		hours = defaultInviteHours
+	}
+
+	code, prefix, hash, err := utils.GenerateInviteCode()
+	if err != nil {
+		log.Printf("Error generating invite code: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	invite := models.Invite{
+		CodeHash:    hash,
+		Prefix:      prefix,
+		Role:        req.Role,
+		MaxUses:     maxUses,
+		ExpiresAt:   time.Now().Add(time.Duration(hours) * time.Hour),
+		CreatedByID: c.Get("userID").(string),
+	}
+	if err := h.DB.Create(&invite).Error; err != nil {
+		log.Printf("Error creating invite: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	if err := h.DB.Where("id = ?", invite.CreatedByID).First(&invite.CreatedBy).Error; err != nil {
+		log.Printf("Error loading invite creator: %v", err)
+	}
+	log.Printf("Invite %v for role %s created by %v", invite.ID, invite.Role, invite.CreatedByID)
+
+	return c.JSON(http.StatusCreated, schemas.InviteCreateResponse{
+		InviteResponse: inviteToResponse(invite),
+		Code:           code,
+	})
+}
+
+// AdminInviteListHandler lists all invites, newest first
+func (h *Handler) AdminInviteListHandler(c echo.Context) error {
+	var invites []models.Invite
+	if err := h.DB.Preload("CreatedBy").Order("created_at DESC").Find(&invites).Error; err != nil {
+		log.Printf("Error listing invites: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	resp := schemas.InviteListResponse{Invites: []schemas.InviteResponse{}}
+	for _, invite := range invites {
+		resp.Invites = append(resp.Invites, inviteToResponse(invite))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// AdminInviteRevokeHandler stops an invite from being redeemed. Accounts
+// already registered with it are kept.
+func (h *Handler) AdminInviteRevokeHandler(c echo.Context) error {
+	inviteID := c.Param("id")
+	if err := uuid.Validate(inviteID); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Invalid invite id"})
+	}
+
+	result := h.DB.Model(&models.Invite{}).Where("id = ? AND revoked_at IS NULL", inviteID).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		log.Printf("Error revoking invite: %v", result.Error)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	if result.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No such invite"})
+	}
+
+	return c.JSON(http.StatusOK, schemas.Message{Status: "Invite revoked"})
+}
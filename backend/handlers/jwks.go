@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"net/http"
+
+	"rulehub/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// JWKSHandler publishes the public keys that access tokens are signed with,
+// so other services can verify them without sharing a secret
+func (h *Handler) JWKSHandler(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, utils.AccessKeyring().JWKS())
+}
@@ -3,36 +3,62 @@ package handlers
 import (
 	"log"
 	"net/http"
-	"os"
+	"rulehub/models"
 	"rulehub/schemas"
 	"rulehub/utils"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 )
 
-// MediaUploadTempHandler generates a presigned URL for temporary file uploads
+// MediaUploadTempHandler issues a presigned POST for one temporary upload.
+// The declared type and size are checked against the configured limits and
+// enforced by the policy; the upload is recorded for the calling user.
 func (h *Handler) MediaUploadTempHandler(c echo.Context) error {
-	// Get bucket name from environment variable
-	bucketName := os.Getenv("MINIO_BUCKET")
-	if bucketName == "" {
-		log.Printf("MINIO_BUCKET environment variable not set")
-		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Server configuration error"})
+	req := c.Get("validatedBody").(*schemas.MediaUploadRequest)
+
+	maxSize, allowedTypes := utils.MediaUploadLimits()
+	contentType := strings.ToLower(req.ContentType)
+	if !slices.Contains(allowedTypes, contentType) {
+		return c.JSON(http.StatusUnsupportedMediaType, echo.Map{"message": "File type not allowed"})
+	}
+	if req.Size > maxSize {
+		return c.JSON(http.StatusRequestEntityTooLarge, echo.Map{"message": "File too large"})
 	}
 
 	// Get the presigned URL expiration time
 	expires := utils.GetPresignedLifetime()
 
-	// Generate a presigned PUT URL for temporary upload
-	fileID, presignedURL, err := utils.GeneratePresignedPutURL(h.MinIOClient, bucketName, expires)
+	upload := models.Upload{
+		FileID:      uuid.New().String(),
+		UserID:      c.Get("userID").(string),
+		FileName:    req.FileName,
+		ContentType: contentType,
+		ExpiresAt:   time.Now().Add(expires),
+	}
+
+	// Generate a presigned POST policy for temporary upload, capped at the
+	// declared size
+	postURL, formData, err := h.Storage.PresignUpload(c.Request().Context(), upload.FileID, contentType, req.Size, expires)
 	if err != nil {
 		log.Printf("Error generating presigned URL: %v", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Error generating upload URL"})
 	}
 
+	if err := h.DB.Create(&upload).Error; err != nil {
+		log.Printf("Error recording upload: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
 	// Return the presigned URL and file ID to the client
 	resp := schemas.MediaUploadResponse{
-		TempURL: presignedURL,
-		FileID:  fileID,
+		TempURL:   postURL,
+		FormData:  formData,
+		FileID:    upload.FileID,
+		ExpiresAt: upload.ExpiresAt,
 	}
 
 	return c.JSON(http.StatusOK, resp)
@@ -40,21 +66,14 @@ func (h *Handler) MediaUploadTempHandler(c echo.Context) error {
 
 // Gen static get url by uuid
 func (h *Handler) MediaGetURLHandler(c echo.Context) error {
-	// Get bucket name from environment variable
-	bucketName := os.Getenv("MINIO_BUCKET")
-	if bucketName == "" {
-		log.Printf("MINIO_BUCKET environment variable not set")
-		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Server configuration error"})
-	}
-
 	// Get the file ID from the request parameters
 	fileID := c.QueryParam("uuid")
 	if fileID == "" {
 		return c.JSON(http.StatusBadRequest, echo.Map{"message": "File ID is required"})
 	}
 
-	// Generate a presigned GET URL for the file
-	presignedURL := utils.GetPermanentObjectURL(bucketName, fileID)
+	// Generate a static URL for the file
+	staticURL := h.Storage.PublicURL(fileID)
 
-	return c.JSON(http.StatusOK, echo.Map{"static_url": presignedURL})
+	return c.JSON(http.StatusOK, echo.Map{"static_url": staticURL})
 }
\ No newline at end of file
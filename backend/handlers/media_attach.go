@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"slices"
+	"time"
+
+	"rulehub/models"
+	"rulehub/storage"
+	"rulehub/utils"
+	"rulehub/workers"
+
+	"gorm.io/gorm"
+)
+
+var (
+	errMediaNotFound = errors.New("media not found")
+	errMediaNotOwned = errors.New("media was not uploaded by the user")
+	errMediaInvalid  = errors.New("media is a malformed image")
+)
+
+// findUpload returns the upload record of key, or nil when the key was never
+// issued by /media/upload-temp
+func findUpload(db *gorm.DB, key string) (*models.Upload, error) {
+	var upload models.Upload
+	err := db.Where("file_id = ?", key).First(&upload).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// objectPromoter marks uploaded objects as permanent and remembers which ones
+// it changed, so that a failed request can put them back
+type objectPromoter struct {
+	store      storage.Storage
+	variants   *workers.VariantGenerator
+	promoted   []string
+	accepted   []string // every key promote succeeded for
+	duplicates []string // fresh uploads replaced by an existing object
+}
+
+func (h *Handler) newObjectPromoter() *objectPromoter {
+	return &objectPromoter{store: h.Storage, variants: h.Variants}
+}
+
+// promote makes an object permanent. Objects that already were permanent are
+// not recorded: reverting must not release media that other articles use.
+func (p *objectPromoter) promote(key string) error {
+	permanent, err := p.store.IsPermanent(context.Background(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return errMediaNotFound
+		}
+		return err
+	}
+	if !permanent {
+		if err := p.store.MarkPermanent(context.Background(), key); err != nil {
+			return err
+		}
+		p.promoted = append(p.promoted, key)
+	}
+	p.accepted = append(p.accepted, key)
+	return nil
+}
+
+// done is called once the request committed. It schedules resized variants
+// of the accepted objects and deletes uploads that turned out to be
+// duplicates. Objects that have variants already are skipped by the
+// generator, so media from before variants existed catches up on edit.
+func (p *objectPromoter) done() {
+	if p.variants != nil {
+		p.variants.Enqueue(p.accepted...)
+	}
+	for _, key := range p.duplicates {
+		if err := p.store.Delete(context.Background(), key); err != nil {
+			log.Printf("Error deleting duplicate upload %v: %v", key, err)
+		}
+	}
+}
+
+// revert demotes everything promote changed. Failures are only logged, the
+// reconciliation job finds such objects later.
+func (p *objectPromoter) revert() {
+	for _, key := range p.promoted {
+		if err := p.store.MarkTemporary(context.Background(), key); err != nil {
+			log.Printf("Error reverting status of %v: %v", key, err)
+		}
+	}
+	p.promoted = nil
+	p.accepted = nil
+	p.duplicates = nil
+}
+
+// sanitizeObject strips metadata from an uploaded image, replacing the object
+// when anything was removed, and describes the stored file
+func sanitizeObject(store storage.Storage, key string) (utils.MediaInfo, error) {
+	object, stat, err := store.Get(context.Background(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return utils.MediaInfo{}, errMediaNotFound
+		}
+		return utils.MediaInfo{}, err
+	}
+	defer object.Close()
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return utils.MediaInfo{}, err
+	}
+
+	stripped, err := utils.StripImageMetadata(data)
+	if errors.Is(err, utils.ErrMalformedImage) {
+		return utils.MediaInfo{}, errMediaInvalid
+	}
+	if err != nil {
+		return utils.MediaInfo{}, err
+	}
+	if !bytes.Equal(stripped, data) {
+		if err := store.Put(context.Background(), key, stat.ContentType, stripped); err != nil {
+			return utils.MediaInfo{}, err
+		}
+		log.Printf("Stripped %d bytes of metadata from %v", len(data)-len(stripped), key)
+	}
+	return utils.InspectMedia(stat.ContentType, stripped), nil
+}
+
+// mediaInfo returns the details of an object and whether it is a fresh
+// upload. Objects that are attached already were sanitized then, their
+// details are copied from any media row, trashed and just replaced ones
+// included.
+func mediaInfo(tx *gorm.DB, promoter *objectPromoter, key string) (utils.MediaInfo, bool, error) {
+	var known models.Media
+	err := tx.Unscoped().Where("s3_key = ? AND sha256 <> ''", key).First(&known).Error
+	if err == nil {
+		return utils.MediaInfo{MimeType: known.MimeType, Size: known.Size, Width: known.Width, Height: known.Height, SHA256: known.SHA256}, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return utils.MediaInfo{}, false, err
+	}
+	info, err := sanitizeObject(promoter.store, key)
+	return info, true, err
+}
+
+// findDuplicate returns the key of an attached object with the given
+// content, or "" when there is none. Only objects that a live media row
+// points at and that are still permanent qualify. While the upload policy of
+// an object is valid its uploader can replace the content, so such objects
+// are not shared, and the content of the chosen one is checked again.
+func findDuplicate(tx *gorm.DB, promoter *objectPromoter, sum, key string) (string, error) {
+	var keys []string
+	if err := tx.Model(&models.Media{}).Where("sha256 = ? AND s3_key <> ?", sum, key).
+		Where("NOT EXISTS (SELECT 1 FROM uploads WHERE uploads.file_id = media.s3_key AND uploads.expires_at > ?)", time.Now()).
+		Order("created_at").Limit(1).Pluck("s3_key", &keys).Error; err != nil {
+		return "", err
+	}
+	if len(keys) == 0 {
+		return "", nil
+	}
+	permanent, err := promoter.store.IsPermanent(context.Background(), keys[0])
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	if !permanent {
+		return "", nil
+	}
+
+	object, _, err := promoter.store.Get(context.Background(), keys[0])
+	if err != nil {
+		return "", err
+	}
+	defer object.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, object); err != nil {
+		return "", err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != sum {
+		log.Printf("Object %v does not match its recorded hash, not sharing it", keys[0])
+		return "", nil
+	}
+	return keys[0], nil
+}
+
+// attachMedia promotes the uploaded objects behind mediaPaths and inserts a
+// media row for each of them inside tx. Every key must have been uploaded by
+// userID, except the ones in kept that the article already had.
+func attachMedia(tx *gorm.DB, promoter *objectPromoter, userID, articleID string, mediaPaths, kept []string) ([]models.Media, error) {
+	var attached []models.Media
+	for _, mediaPath := range mediaPaths {
+		// Extract the S3 key from the media path (which contains the temporary file location)
+		s3Key := extractS3KeyFromPath(mediaPath)
+
+		// Keys are guessable, only the uploader may attach a fresh upload
+		upload, err := findUpload(tx, s3Key)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(kept, s3Key) && (upload == nil || upload.UserID != userID) {
+			return nil, errMediaNotOwned
+		}
+		fileName := getOriginalFileName(mediaPath)
+		if upload != nil {
+			fileName = upload.FileName
+		}
+
+		// Metadata is stripped before the file becomes permanent
+		info, fresh, err := mediaInfo(tx, promoter, s3Key)
+		if err != nil {
+			log.Printf("Error sanitizing %v: %v", s3Key, err)
+			return nil, err
+		}
+
+		// Identical content is stored once: the row points at the object
+		// attached first and the fresh upload is dropped. Objects are shared
+		// by their media rows and only deleted when the last one goes.
+		if fresh {
+			original, err := findDuplicate(tx, promoter, info.SHA256, s3Key)
+			if err != nil {
+				return nil, err
+			}
+			if original != "" {
+				log.Printf("Upload %v duplicates %v, sharing the object", s3Key, original)
+				promoter.duplicates = append(promoter.duplicates, s3Key)
+				s3Key = original
+			}
+		}
+
+		// Change file status from temporary to permanent
+		if err := promoter.promote(s3Key); err != nil {
+			log.Printf("Error changing status of %v to permanent: %v", s3Key, err)
+			return nil, err
+		}
+
+		// Save the permanent file info in database
+		media := models.Media{
+			FileName:  fileName,
+			S3Key:     s3Key,
+			ArticleID: articleID,
+			MimeType:  info.MimeType,
+			Size:      info.Size,
+			Width:     info.Width,
+			Height:    info.Height,
+			SHA256:    info.SHA256,
+		}
+		if err := tx.Create(&media).Error; err != nil {
+			return nil, err
+		}
+		// Objects kept or shared with other articles may have variants already
+		if err := tx.Where("source_key = ?", s3Key).Find(&media.Variants).Error; err != nil {
+			return nil, err
+		}
+		attached = append(attached, media)
+	}
+	return attached, nil
+}
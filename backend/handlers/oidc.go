@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"rulehub/models"
+	"rulehub/schemas"
+	"rulehub/utils"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const oidcStateCookieName = "oidc_state"
+
+// Reasons the callback reports to the frontend in the oidc_error parameter
+const (
+	oidcErrorProvider      = "provider_error"
+	oidcErrorInvalidState  = "invalid_state"
+	oidcErrorNotLinked     = "not_linked"
+	oidcErrorIdentityInUse = "identity_in_use"
+	oidcErrorInternal      = "internal_error"
+)
+
+func (h *Handler) oidcDisabled(c echo.Context) error {
+	return c.JSON(http.StatusNotFound, echo.Map{"message": "OIDC login is not configured"})
+}
+
+// startOIDCFlow keeps state, nonce and PKCE verifier in a signed cookie and
+// returns the provider URL to send the browser to
+func (h *Handler) startOIDCFlow(c echo.Context, linkUserID string) (string, error) {
+	var state utils.OIDCState
+	var err error
+	for _, value := range []*string{&state.State, &state.Nonce, &state.Verifier} {
+		if *value, err = utils.RandomURLString(32); err != nil {
+			return "", err
+		}
+	}
+	state.LinkUserID = linkUserID
+
+	authURL, err := h.OIDC.AuthCodeURL(c.Request().Context(), state.State, state.Nonce, state.Verifier)
+	if err != nil {
+		return "", err
+	}
+	stateToken, err := utils.GenerateOIDCStateToken(state)
+	if err != nil {
+		return "", err
+	}
+
+	cookie := new(http.Cookie)
+	cookie.Name = oidcStateCookieName
+	cookie.Value = stateToken
+	cookie.HttpOnly = true
+	cookie.Path = "/"
+	// Lax, so the cookie comes back with the top-level redirect from the provider
+	cookie.SameSite = http.SameSiteLaxMode
+	cookie.MaxAge = int(utils.OIDCStateLifetime.Seconds())
+	c.SetCookie(cookie)
+	return authURL, nil
+}
+
+// oidcRedirect sends the browser back to the frontend with the outcome
+func (h *Handler) oidcRedirect(c echo.Context, key, value string) error {
+	separator := "?"
+	if strings.Contains(h.OIDC.PostLoginURL, "?") {
+		separator = "&"
+	}
+	return c.Redirect(http.StatusFound, h.OIDC.PostLoginURL+separator+url.Values{key: {value}}.Encode())
+}
+
+// OIDCLoginHandler starts a login at the OIDC provider
+func (h *Handler) OIDCLoginHandler(c echo.Context) error {
+	if h.OIDC == nil {
+		return h.oidcDisabled(c)
+	}
+
+	authURL, err := h.startOIDCFlow(c, "")
+	if err != nil {
+		log.Printf("Error starting OIDC login: %v", err)
+		return c.JSON(http.StatusBadGateway, echo.Map{"message": "OIDC provider unavailable"})
+	}
+	return c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCLinkHandler starts linking an identity of the OIDC provider to the
+// current user. The browser has to open the returned URL itself, since a
+// redirect can not carry the Authorization header.
+func (h *Handler) OIDCLinkHandler(c echo.Context) error {
+	if h.OIDC == nil {
+		return h.oidcDisabled(c)
+	}
+
+	authURL, err := h.startOIDCFlow(c, c.Get("userID").(string))
+	if err != nil {
+		log.Printf("Error starting OIDC link: %v", err)
+		return c.JSON(http.StatusBadGateway, echo.Map{"message": "OIDC provider unavailable"})
+	}
+	return c.JSON(http.StatusOK, schemas.OIDCLinkResponse{AuthorizationURL: authURL})
+}
+
+// OIDCCallbackHandler finishes a login or link started by this browser. On
+// login a session is started and its refresh cookie set; the frontend then
+// gets an access token from /auth/refresh. Two-factor authentication is left
+// to the provider.
+func (h *Handler) OIDCCallbackHandler(c echo.Context) error {
+	if h.OIDC == nil {
+		return h.oidcDisabled(c)
+	}
+
+	// The state is single-use, whatever the outcome
+	stateCookie, err := c.Cookie(oidcStateCookieName)
+	clearCookie := new(http.Cookie)
+	clearCookie.Name = oidcStateCookieName
+	clearCookie.Path = "/"
+	clearCookie.MaxAge = -1
+	c.SetCookie(clearCookie)
+	if err != nil {
+		return h.oidcRedirect(c, "oidc_error", oidcErrorInvalidState)
+	}
+	state, err := utils.ValidateOIDCStateToken(stateCookie.Value)
+	if err != nil || subtle.ConstantTimeCompare([]byte(state.State), []byte(c.QueryParam("state"))) != 1 {
+		return h.oidcRedirect(c, "oidc_error", oidcErrorInvalidState)
+	}
+
+	if providerError := c.QueryParam("error"); providerError != "" {
+		log.Printf("OIDC provider refused the login: %s", providerError)
+		return h.oidcRedirect(c, "oidc_error", oidcErrorProvider)
+	}
+	ctx := c.Request().Context()
+	idToken, err := h.OIDC.Exchange(ctx, c.QueryParam("code"), state.Verifier)
+	if err != nil {
+		log.Printf("Error exchanging OIDC code: %v", err)
+		return h.oidcRedirect(c, "oidc_error", oidcErrorProvider)
+	}
+	claims, err := h.OIDC.VerifyIDToken(ctx, idToken, state.Nonce)
+	if err != nil {
+		log.Printf("Invalid OIDC ID token: %v", err)
+		return h.oidcRedirect(c, "oidc_error", oidcErrorProvider)
+	}
+
+	var identity models.UserIdentity
+	err = h.DB.Preload("User").Where("issuer = ? AND subject = ?", claims.Issuer, claims.Subject).First(&identity).Error
+	found := err == nil
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Error looking up identity: %v", err)
+		return h.oidcRedirect(c, "oidc_error", oidcErrorInternal)
+	}
+	// Identities of deleted accounts are removed with them, one left over
+	// links to nobody
+	if found && identity.User.ID == uuid.Nil {
+		log.Printf("OIDC subject %s is linked to a deleted user", claims.Subject)
+		if err := h.DB.Unscoped().Delete(&identity).Error; err != nil {
+			log.Printf("Error deleting stale identity: %v", err)
+			return h.oidcRedirect(c, "oidc_error", oidcErrorInternal)
+		}
+		found, identity = false, models.UserIdentity{}
+	}
+
+	if state.LinkUserID != "" {
+		return h.finishOIDCLink(c, state.LinkUserID, claims, found, identity)
+	}
+
+	user := identity.User
+	switch {
+	case found:
+	case h.OIDC.AutoProvision:
+		if user, identity, err = h.provisionOIDCUser(claims); err != nil {
+			log.Printf("Error provisioning OIDC user: %v", err)
+			return h.oidcRedirect(c, "oidc_error", oidcErrorInternal)
+		}
+		log.Printf("Provisioned user %s for OIDC subject %s", user.Username, claims.Subject)
+	default:
+		// Accounts are never matched by email, the provider may not own the address
+		log.Printf("OIDC subject %s is not linked to any user", claims.Subject)
+		return h.oidcRedirect(c, "oidc_error", oidcErrorNotLinked)
+	}
+
+	if err := h.DB.Model(&identity).Updates(map[string]interface{}{"email": claims.Email, "last_login_at": time.Now()}).Error; err != nil {
+		log.Printf("Error updating OIDC identity: %v", err)
+	}
+
+	if _, err := h.startSession(c, user); err != nil {
+		log.Printf("Error starting session: %v", err)
+		return h.oidcRedirect(c, "oidc_error", oidcErrorInternal)
+	}
+	return h.oidcRedirect(c, "oidc", "ok")
+}
+
+// finishOIDCLink attaches the identity to the user that started the link. An
+// identity already linked to someone else is not moved.
+func (h *Handler) finishOIDCLink(c echo.Context, userID string, claims *utils.OIDCClaims, found bool, identity models.UserIdentity) error {
+	if found {
+		if identity.UserID != userID {
+			log.Printf("OIDC subject %s is already linked to another user", claims.Subject)
+			return h.oidcRedirect(c, "oidc_error", oidcErrorIdentityInUse)
+		}
+		return h.oidcRedirect(c, "oidc", "linked")
+	}
+
+	identity = models.UserIdentity{
+		UserID:  userID,
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+		Email:   claims.Email,
+	}
+	if err := h.DB.Create(&identity).Error; err != nil {
+		log.Printf("Error linking identity: %v", err)
+		return h.oidcRedirect(c, "oidc_error", oidcErrorInternal)
+	}
+	log.Printf("Linked OIDC subject %s to user %s", claims.Subject, userID)
+	return h.oidcRedirect(c, "oidc", "linked")
+}
+
+// provisionOIDCUser creates a user without a local password for a new
+// identity. Registration modes do not apply, enabling auto-provisioning is
+// the admin's decision.
+func (h *Handler) provisionOIDCUser(claims *utils.OIDCClaims) (models.User, models.UserIdentity, error) {
+	var user models.User
+	var identity models.UserIdentity
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		first, err := models.LockFirstUser(tx)
+		if err != nil {
+			return err
+		}
+		user = models.User{Role: models.RoleAuthor}
+		if first {
+			user.Role = models.RoleAdmin
+		}
+
+		base := oidcUsername(claims)
+		for attempt := 0; ; attempt++ {
+			user.Username = base
+			if attempt > 0 {
+				user.Username = fmt.Sprintf("%s_%04d", truncate(base, 15), rand.IntN(10000))
+			}
+			var taken int64
+			if err := tx.Model(&models.User{}).Where("username = ?", user.Username).Count(&taken).Error; err != nil {
+				return err
+			}
+			if taken == 0 {
+				break
+			}
+			if attempt == 5 {
+				return fmt.Errorf("no free username for %q", base)
+			}
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+
+		identity = models.UserIdentity{
+			UserID:  user.ID.String(),
+			Issuer:  claims.Issuer,
+			Subject: claims.Subject,
+			Email:   claims.Email,
+		}
+		return tx.Create(&identity).Error
+	})
+	return user, identity, err
+}
+
+// oidcUsername derives a valid username from the provider claims
+func oidcUsername(claims *utils.OIDCClaims) string {
+	candidate := claims.PreferredUsername
+	if candidate == "" {
+		candidate, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	var username strings.Builder
+	for _, ch := range candidate {
+		if ch < 128 && (ch == '_' || ch == '-' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9') {
+			username.WriteRune(ch)
+		}
+	}
+	if username.Len() < 3 {
+		return "user"
+	}
+	return truncate(username.String(), 20)
+}
+
+// UserIdentityListHandler lists the OIDC identities linked to the current user
+func (h *Handler) UserIdentityListHandler(c echo.Context) error {
+	var identities []models.UserIdentity
+	if err := h.DB.Where("user_id = ?", c.Get("userID").(string)).Order("created_at").Find(&identities).Error; err != nil {
+		log.Printf("Error listing identities: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	resp := schemas.IdentityListResponse{Identities: []schemas.IdentityResponse{}}
+	for _, identity := range identities {
+		resp.Identities = append(resp.Identities, schemas.IdentityResponse{
+			ID:          identity.ID.String(),
+			Issuer:      identity.Issuer,
+			Subject:     identity.Subject,
+			Email:       identity.Email,
+			CreatedAt:   identity.CreatedAt,
+			LastLoginAt: identity.LastLoginAt,
+		})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// UserIdentityUnlinkHandler removes a linked identity. The last identity of
+// a user without a password stays, it is their only way to sign in.
+func (h *Handler) UserIdentityUnlinkHandler(c echo.Context) error {
+	identityID := c.Param("id")
+	if err := uuid.Validate(identityID); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Invalid identity id"})
+	}
+	userID := c.Get("userID").(string)
+
+	var user models.User
+	if err := h.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		log.Printf("User not found: %v", err)
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No such user"})
+	}
+	if user.Password == "" {
+		var count int64
+		if err := h.DB.Model(&models.UserIdentity{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			log.Printf("Error counting identities: %v", err)
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+		}
+		if count <= 1 {
+			return c.JSON(http.StatusConflict, echo.Map{"message": "Can not unlink the only way to sign in"})
+		}
+	}
+
+	// Deleted for good, so the identity can be linked again later
+	result := h.DB.Unscoped().Where("id = ? AND user_id = ?", identityID, userID).Delete(&models.UserIdentity{})
+	if result.Error != nil {
+		log.Printf("Error unlinking identity: %v", result.Error)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	if result.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No such identity"})
+	}
+
+	return c.JSON(http.StatusOK, schemas.Message{Status: "Identity unlinked"})
+}
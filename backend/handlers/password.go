@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"rulehub/models"
+	"rulehub/schemas"
+	"rulehub/utils"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultPasswordResetTTL = time.Hour
+
+var errInvalidResetToken = errors.New("invalid or expired reset token")
+
+// setPassword stores a new password and drops reset tokens that are still
+// pending, so a token issued before the change can not undo it
+func setPassword(tx *gorm.DB, userID, password string) error {
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("password", passwordHash).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.PasswordReset{}).Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
+
+// UserPasswordChangeHandler changes the password of the current user. Every
+// other session is revoked; the one making the change stays signed in.
+func (h *Handler) UserPasswordChangeHandler(c echo.Context) error {
+	req := c.Get("validatedBody").(*schemas.PasswordChangeRequest)
+
+	var user models.User
+	if err := h.DB.Where("id = ?", c.Get("userID").(string)).First(&user).Error; err != nil {
+		log.Printf("User not found: %v", err)
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No such user"})
+	}
+
+	// A stolen access token should not give unlimited guesses at the password
+	if wait := h.LoginThrottle.Check(user.Username, c.RealIP()); wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+	if !utils.CheckPassword(req.CurrentPassword, user.Password) {
+		log.Printf("Invalid current password for user: %s", user.Username)
+		h.LoginThrottle.Failure(user.Username, c.RealIP())
+		return c.JSON(http.StatusForbidden, echo.Map{"message": "Invalid current password"})
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := setPassword(tx, user.ID.String(), req.NewPassword); err != nil {
+			return err
+		}
+		return revokeSessions(tx, "user_id = ? AND id <> ?", user.ID.String(), c.Get("sessionID").(string))
+	})
+	if err != nil {
+		log.Printf("Error changing password: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	log.Printf("User %s changed their password", user.Username)
+
+	return c.JSON(http.StatusOK, schemas.Message{Status: "Password changed"})
+}
+
+// AdminPasswordResetCreateHandler issues a one-time token that lets the user
+// set a new password at /auth/reset. Earlier pending tokens of the user are
+// dropped. The token is only shown in this response.
+func (h *Handler) AdminPasswordResetCreateHandler(c echo.Context) error {
+	username := c.Param("username")
+
+	var user models.User
+	if err := h.DB.Where("username = ?", username).First(&user).Error; err != nil {
+		log.Printf("User not found: %v", username)
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No such user"})
+	}
+
+	token, hash, err := utils.GenerateResetToken()
+	if err != nil {
+		log.Printf("Error generating reset token: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	reset := models.PasswordReset{
+		UserID:      user.ID.String(),
+		TokenHash:   hash,
+		ExpiresAt:   time.Now().Add(utils.GetDurationEnv("PASSWORD_RESET_TTL", defaultPasswordResetTTL)),
+		CreatedByID: c.Get("userID").(string),
+	}
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.PasswordReset{}).Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&reset).Error
+	})
+	if err != nil {
+		log.Printf("Error creating password reset: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	log.Printf("Password reset for %s issued by %v", user.Username, c.Get("userID"))
+
+	return c.JSON(http.StatusCreated, schemas.PasswordResetCreateResponse{
+		Username:  user.Username,
+		Token:     token,
+		ExpiresAt: reset.ExpiresAt,
+	})
+}
+
+// UserPasswordResetHandler redeems a reset token. The new password replaces
+// the old one and every session of the user is revoked.
+func (h *Handler) UserPasswordResetHandler(c echo.Context) error {
+	req := c.Get("validatedBody").(*schemas.PasswordResetRequest)
+
+	var username string
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// The row is locked, so a token can not be redeemed twice in parallel
+		var reset models.PasswordReset
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("User").
+			Where("token_hash = ?", utils.HashResetToken(req.Token)).First(&reset).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+		if !reset.Usable() {
+			return errInvalidResetToken
+		}
+		username = reset.User.Username
+
+		if err := setPassword(tx, reset.UserID, req.NewPassword); err != nil {
+			return err
+		}
+		return revokeSessions(tx, "user_id = ?", reset.UserID)
+	})
+	if errors.Is(err, errInvalidResetToken) {
+		return c.JSON(http.StatusForbidden, echo.Map{"message": "Invalid or expired reset token"})
+	}
+	if err != nil {
+		log.Printf("Error resetting password: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	// The user proved control of the account, earlier failed guesses no longer matter
+	h.LoginThrottle.Success(username)
+	log.Printf("Password of %s reset", username)
+
+	return c.JSON(http.StatusOK, schemas.Message{Status: "Password reset"})
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"rulehub/middleware"
+	"rulehub/models"
+	"rulehub/schemas"
+	"rulehub/utils"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	googleUUID "github.com/google/uuid"
+)
+
+// recordRevision stores the current title and content of an article as its
+// next revision
+func recordRevision(db *gorm.DB, article *models.Article, userID string, restoredFrom *int) error {
+	number, err := models.NextRevisionNumber(db, article.ID.String())
+	if err != nil {
+		return err
+	}
+	revision := models.ArticleRevision{
+		ArticleID:    article.ID.String(),
+		Number:       number,
+		Title:        article.Title,
+		Content:      article.Content,
+		UserID:       userID,
+		RestoredFrom: restoredFrom,
+	}
+	return db.Create(&revision).Error
+}
+
+func revisionToSummary(revision models.ArticleRevision) schemas.RevisionSummary {
+	return schemas.RevisionSummary{
+		Number:         revision.Number,
+		Title:          revision.Title,
+		AuthorUsername: revision.User.Username,
+		RestoredFrom:   revision.RestoredFrom,
+		CreatedAt:      revision.CreatedAt,
+	}
+}
+
+// unscoped preloads users of deleted accounts too, which revisions keep
+// pointing at
+func unscoped(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// findRevision loads one revision of an article with its author. Revisions of
+// articles in the trash are not found, like the articles themselves.
+func (h *Handler) findRevision(articleID string, number int) (models.ArticleRevision, error) {
+	var revision models.ArticleRevision
+	if err := h.DB.Select("id").Where("id = ?", articleID).First(&models.Article{}).Error; err != nil {
+		return revision, err
+	}
+	err := h.DB.Preload("User", unscoped).Where("article_id = ? AND number = ?", articleID, number).First(&revision).Error
+	return revision, err
+}
+
+func (h *Handler) ArticleRevisionListHandler(c echo.Context) error {
+	uuid := c.Param("uuid")
+	if err := googleUUID.Validate(uuid); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "UUID is required"})
+	}
+
+	var article models.Article
+	if err := h.DB.Where("id = ?", uuid).First(&article).Error; err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No such article"})
+	}
+
+	var revisions []models.ArticleRevision
+	if err := h.DB.Preload("User", unscoped).Where("article_id = ?", uuid).Order("number DESC").Find(&revisions).Error; err != nil {
+		log.Printf("Error listing revisions: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	resp := schemas.RevisionListResponse{Revisions: []schemas.RevisionSummary{}}
+	for _, revision := range revisions {
+		resp.Revisions = append(resp.Revisions, revisionToSummary(revision))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handler) ArticleRevisionGetHandler(c echo.Context) error {
+	uuid := c.Param("uuid")
+	if err := googleUUID.Validate(uuid); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "UUID is required"})
+	}
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Revision number is required"})
+	}
+
+	revision, err := h.findRevision(uuid, number)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No such revision"})
+	}
+
+	return c.JSON(http.StatusOK, schemas.RevisionResponse{
+		RevisionSummary: revisionToSummary(revision),
+		Content:         revision.Content,
+	})
+}
+
+// ArticleRevisionDiffHandler returns a unified diff of the title and content
+// between two revisions
+func (h *Handler) ArticleRevisionDiffHandler(c echo.Context) error {
+	uuid := c.Param("uuid")
+	if err := googleUUID.Validate(uuid); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "UUID is required"})
+	}
+	query := c.Get("validatedBody").(*schemas.RevisionDiffQuery)
+
+	from, err := h.findRevision(uuid, query.From)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No such revision"})
+	}
+	to, err := h.findRevision(uuid, query.To)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No such revision"})
+	}
+
+	// The title goes first so that renames show up in the diff too
+	oldText := "# " + from.Title + "\n\n" + from.Content
+	newText := "# " + to.Title + "\n\n" + to.Content
+	diff := utils.UnifiedDiff(fmt.Sprintf("revision/%d", from.Number), fmt.Sprintf("revision/%d", to.Number), oldText, newText)
+
+	return c.JSON(http.StatusOK, schemas.RevisionDiffResponse{
+		From: from.Number,
+		To:   to.Number,
+		Diff: diff,
+	})
+}
+
+// ArticleRevisionRestoreHandler makes an old revision the current text of the
+// article. History is kept: the restore is recorded as a new revision.
+func (h *Handler) ArticleRevisionRestoreHandler(c echo.Context) error {
+	uuid := c.Param("uuid")
+	if err := googleUUID.Validate(uuid); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "UUID is required"})
+	}
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Revision number is required"})
+	}
+
+	var article models.Article
+	if err := h.DB.Preload("User").Preload("Media.Variants").Where("id = ?", uuid).First(&article).Error; err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No such article"})
+	}
+
+	userID := c.Get("userID").(string)
+	if !models.CanModifyArticle(userID, c.Get("userRole").(string), &article) {
+		log.Printf("User %v is not allowed to restore article %v", userID, uuid)
+		return middleware.Forbidden(c)
+	}
+
+	revision, err := h.findRevision(uuid, number)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No such revision"})
+	}
+
+	article.Title = revision.Title
+	article.Content = revision.Content
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&article).Updates(map[string]interface{}{
+			"title":   article.Title,
+			"content": article.Content,
+		}).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, &article, userID, &revision.Number)
+	})
+	if err != nil {
+		log.Printf("Error restoring revision: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, h.articleToResponse(article))
+}
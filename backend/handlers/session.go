@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"rulehub/models"
+	"rulehub/schemas"
+	"rulehub/utils"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// refreshReuseGrace lets a client that sends the same refresh token twice in
+// quick succession, e.g. from parallel requests after a 401, keep its session
+const refreshReuseGrace = 10 * time.Second
+
+const refreshCookieName = "refresh_token"
+
+// startSession creates a session for a user that has just authenticated, sets
+// the refresh cookie and returns an access token
+func (h *Handler) startSession(c echo.Context, user models.User) (string, error) {
+	now := time.Now()
+	session := models.Session{
+		UserID:     user.ID.String(),
+		UserAgent:  truncate(c.Request().UserAgent(), 256),
+		IP:         truncate(c.RealIP(), 64),
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(utils.RefreshTokenLifetime),
+	}
+
+	var refreshToken string
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		var err error
+		refreshToken, err = issueRefreshToken(tx, &session)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	setRefreshCookie(c, refreshToken, session.ExpiresAt)
+	return utils.GenerateAccessToken(user.ID.String(), user.Username, user.Role, session.ID.String())
+}
+
+// issueRefreshToken records a new token of the session family and signs it
+func issueRefreshToken(tx *gorm.DB, session *models.Session) (string, error) {
+	token := models.RefreshToken{
+		JTI:       uuid.New().String(),
+		SessionID: session.ID.String(),
+		ExpiresAt: session.ExpiresAt,
+	}
+	if err := tx.Create(&token).Error; err != nil {
+		return "", err
+	}
+	return utils.GenerateRefreshToken(session.UserID, token.JTI, token.ExpiresAt)
+}
+
+// revokeSessions revokes the active sessions matched by the query conditions
+func revokeSessions(db *gorm.DB, query interface{}, args ...interface{}) error {
+	return db.Model(&models.Session{}).Where(query, args...).Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now()).Error
+}
+
+// UserSessionListHandler lists the active sessions of the current user, most
+// recently used first
+func (h *Handler) UserSessionListHandler(c echo.Context) error {
+	var sessions []models.Session
+	if err := h.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", c.Get("userID").(string), time.Now()).
+		Order("last_used_at DESC").Find(&sessions).Error; err != nil {
+		log.Printf("Error listing sessions: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	resp := schemas.SessionListResponse{Sessions: []schemas.SessionResponse{}}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, schemas.SessionResponse{
+			ID:         session.ID.String(),
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID.String() == c.Get("sessionID"),
+		})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// UserSessionRevokeHandler revokes one session of the current user. Its
+// access tokens stop working immediately and its refresh token is rejected.
+func (h *Handler) UserSessionRevokeHandler(c echo.Context) error {
+	sessionID := c.Param("id")
+	if err := uuid.Validate(sessionID); err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Invalid session id"})
+	}
+
+	result := h.DB.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, c.Get("userID").(string)).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		log.Printf("Error revoking session: %v", result.Error)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	if result.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No such session"})
+	}
+	if sessionID == c.Get("sessionID") {
+		clearRefreshCookie(c)
+	}
+
+	return c.JSON(http.StatusOK, schemas.Message{Status: "Session revoked"})
+}
+
+// Set the refresh token in an HttpOnly cookie
+func setRefreshCookie(c echo.Context, value string, expires time.Time) {
+	cookie := new(http.Cookie)
+	cookie.Name = refreshCookieName
+	cookie.Value = value
+	cookie.HttpOnly = true
+	cookie.Path = "/"
+	cookie.Expires = expires
+	c.SetCookie(cookie)
+}
+
+func clearRefreshCookie(c echo.Context) {
+	cookie := new(http.Cookie)
+	cookie.Name = refreshCookieName
+	cookie.Value = ""
+	cookie.HttpOnly = true
+	cookie.Path = "/"
+	cookie.MaxAge = -1
+	c.SetCookie(cookie)
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"rulehub/models"
+	"rulehub/schemas"
+	"rulehub/utils"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const recoveryCodeCount = 10
+
+// totpIssuer is the account label shown by authenticator apps
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "RuleHub"
+}
+
+// findTwoFactor loads the TOTP settings of a user, nil when there are none
+func findTwoFactor(db *gorm.DB, userID string) (*models.TwoFactor, error) {
+	var twoFactor models.TwoFactor
+	err := db.Where("user_id = ?", userID).First(&twoFactor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &twoFactor, nil
+}
+
+// checkTOTP validates a TOTP code and marks its time step as used. The update
+// is conditional, so two requests racing with the same code cannot both win.
+func checkTOTP(db *gorm.DB, twoFactor *models.TwoFactor, code string) (bool, error) {
+	counter, ok := utils.ValidateTOTP(twoFactor.Secret, code, time.Now(), uint64(twoFactor.LastCounter))
+	if !ok {
+		return false, nil
+	}
+	result := db.Model(&models.TwoFactor{}).
+		Where("user_id = ? AND last_counter < ?", twoFactor.UserID, counter).
+		Update("last_counter", counter)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	twoFactor.LastCounter = int64(counter)
+	return result.RowsAffected == 1, nil
+}
+
+// useRecoveryCode spends one of the user's unused recovery codes. The
+// update is conditional, so a code can only be used once.
+func useRecoveryCode(db *gorm.DB, userID, code string) (bool, error) {
+	result := db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, utils.HashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// verifySecondFactor accepts either a TOTP code or a recovery code
+func verifySecondFactor(db *gorm.DB, twoFactor *models.TwoFactor, code string) (bool, error) {
+	if len(code) == utils.TOTPDigits {
+		return checkTOTP(db, twoFactor, code)
+	}
+	return useRecoveryCode(db, twoFactor.UserID, code)
+}
+
+// replaceRecoveryCodes drops the user's recovery codes and issues new ones
+func replaceRecoveryCodes(tx *gorm.DB, userID string) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	rows := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		rows = append(rows, models.RecoveryCode{UserID: userID, CodeHash: utils.HashRecoveryCode(code)})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// UserLoginTwoFactorHandler completes a login started with a password by
+// checking the second factor, then starts the session
+func (h *Handler) UserLoginTwoFactorHandler(c echo.Context) error {
+	req := c.Get("validatedBody").(*schemas.TwoFactorLoginRequest)
+
+	userID, err := utils.ValidateChallengeToken(req.ChallengeToken)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "Invalid or expired challenge"})
+	}
+
+	var user models.User
+	if err := h.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "Invalid or expired challenge"})
+	}
+	twoFactor, err := findTwoFactor(h.DB, userID)
+	if err != nil {
+		log.Printf("Error loading two-factor settings: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	if twoFactor == nil || !twoFactor.Enabled() {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "Invalid or expired challenge"})
+	}
+
+	// Codes are short, so their guesses count against the same limits as passwords
+	if wait := h.LoginThrottle.Check(user.Username, c.RealIP()); wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+	ok, err := verifySecondFactor(h.DB, twoFactor, req.Code)
+	if err != nil {
+		log.Printf("Error verifying second factor: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	if !ok {
+		log.Printf("Invalid second factor for user: %s", user.Username)
+		h.LoginThrottle.Failure(user.Username, c.RealIP())
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "Invalid code"})
+	}
+	h.LoginThrottle.Success(user.Username)
+
+	accessToken, err := h.startSession(c, user)
+	if err != nil {
+		log.Printf("Error starting session: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, schemas.SignInResponse{
+		AccessJWT: accessToken,
+	})
+}
+
+// TwoFactorStatusHandler reports whether the current user has 2FA enabled
+func (h *Handler) TwoFactorStatusHandler(c echo.Context) error {
+	userID := c.Get("userID").(string)
+	twoFactor, err := findTwoFactor(h.DB, userID)
+	if err != nil {
+		log.Printf("Error loading two-factor settings: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	resp := schemas.TwoFactorStatusResponse{}
+	if twoFactor != nil && twoFactor.Enabled() {
+		resp.Enabled = true
+		resp.EnabledAt = twoFactor.EnabledAt
+		if err := h.DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).
+			Count(&resp.RecoveryCodesLeft).Error; err != nil {
+			log.Printf("Error counting recovery codes: %v", err)
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+		}
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// TwoFactorEnrollHandler generates a new TOTP secret. It only takes effect
+// after a code from it is confirmed.
+func (h *Handler) TwoFactorEnrollHandler(c echo.Context) error {
+	userID := c.Get("userID").(string)
+	twoFactor, err := findTwoFactor(h.DB, userID)
+	if err != nil {
+		log.Printf("Error loading two-factor settings: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	if twoFactor != nil && twoFactor.Enabled() {
+		return c.JSON(http.StatusConflict, echo.Map{"message": "Two-factor authentication is already enabled"})
+	}
+
+	var user models.User
+	if err := h.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "User not found"})
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		log.Printf("Error generating TOTP secret: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	if err := h.DB.Save(&models.TwoFactor{UserID: userID, Secret: secret}).Error; err != nil {
+		log.Printf("Error saving TOTP secret: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, schemas.TwoFactorEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: utils.TOTPURI(totpIssuer(), user.Username, secret),
+	})
+}
+
+// TwoFactorConfirmHandler enables 2FA once the user proves their app
+// generates valid codes, and returns the first set of recovery codes
+func (h *Handler) TwoFactorConfirmHandler(c echo.Context) error {
+	req := c.Get("validatedBody").(*schemas.TwoFactorCodeRequest)
+	userID := c.Get("userID").(string)
+
+	twoFactor, err := findTwoFactor(h.DB, userID)
+	if err != nil {
+		log.Printf("Error loading two-factor settings: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	if twoFactor == nil {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Two-factor enrollment not started"})
+	}
+	if twoFactor.Enabled() {
+		return c.JSON(http.StatusConflict, echo.Map{"message": "Two-factor authentication is already enabled"})
+	}
+
+	var codes []string
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		ok, err := checkTOTP(tx, twoFactor, req.Code)
+		if err != nil || !ok {
+			return err
+		}
+		if err := tx.Model(twoFactor).Update("enabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		log.Printf("Error enabling two-factor authentication: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	if codes == nil {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "Invalid code"})
+	}
+	log.Printf("Two-factor authentication enabled for user %v", userID)
+
+	return c.JSON(http.StatusOK, schemas.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// TwoFactorDisableHandler turns 2FA off. Both the password and a second
+// factor are required, so a stolen session alone cannot do it.
+func (h *Handler) TwoFactorDisableHandler(c echo.Context) error {
+	req := c.Get("validatedBody").(*schemas.TwoFactorDisableRequest)
+	userID := c.Get("userID").(string)
+
+	var user models.User
+	if err := h.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "User not found"})
+	}
+	if !utils.CheckPassword(req.Password, user.Password) {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "Invalid password"})
+	}
+
+	twoFactor, err := findTwoFactor(h.DB, userID)
+	if err != nil {
+		log.Printf("Error loading two-factor settings: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	if twoFactor == nil || !twoFactor.Enabled() {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Two-factor authentication is not enabled"})
+	}
+
+	ok, err := verifySecondFactor(h.DB, twoFactor, req.Code)
+	if err != nil {
+		log.Printf("Error verifying second factor: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "Invalid code"})
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.TwoFactor{}).Error
+	})
+	if err != nil {
+		log.Printf("Error disabling two-factor authentication: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	log.Printf("Two-factor authentication disabled for user %v", userID)
+
+	return c.JSON(http.StatusOK, schemas.Message{Status: "Two-factor authentication disabled"})
+}
+
+// TwoFactorRecoveryCodesHandler replaces all recovery codes. A TOTP code is
+// required; a recovery code would let a leaked list renew itself.
+func (h *Handler) TwoFactorRecoveryCodesHandler(c echo.Context) error {
+	req := c.Get("validatedBody").(*schemas.TwoFactorCodeRequest)
+	userID := c.Get("userID").(string)
+
+	twoFactor, err := findTwoFactor(h.DB, userID)
+	if err != nil {
+		log.Printf("Error loading two-factor settings: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	if twoFactor == nil || !twoFactor.Enabled() {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Two-factor authentication is not enabled"})
+	}
+
+	var codes []string
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		ok, err := checkTOTP(tx, twoFactor, req.Code)
+		if err != nil || !ok {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		log.Printf("Error regenerating recovery codes: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+	if codes == nil {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "Invalid code"})
+	}
+
+	return c.JSON(http.StatusOK, schemas.RecoveryCodesResponse{RecoveryCodes: codes})
+}
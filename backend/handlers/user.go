@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"rulehub/models"
+	"rulehub/schemas"
+	"rulehub/utils"
+	"rulehub/workers"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// authorSummary is the short profile shown next to the articles of user
+func (h *Handler) authorSummary(user models.User) schemas.AuthorSummary {
+	summary := schemas.AuthorSummary{
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+	}
+	if user.AvatarKey != "" {
+		summary.AvatarURL = h.Storage.PublicURL(user.AvatarKey)
+	}
+	return summary
+}
+
+func (h *Handler) userProfileResponse(user models.User) schemas.UserProfileResponse {
+	return schemas.UserProfileResponse{
+		AuthorSummary: h.authorSummary(user),
+		Bio:           user.Bio,
+		Role:          user.Role,
+		CreatedAt:     user.CreatedAt,
+	}
+}
+
+// UserMeGetHandler returns the profile of the current user
+func (h *Handler) UserMeGetHandler(c echo.Context) error {
+	var user models.User
+	if err := h.DB.Where("id = ?", c.Get("userID").(string)).First(&user).Error; err != nil {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "User not found"})
+	}
+	return c.JSON(http.StatusOK, h.userProfileResponse(user))
+}
+
+var errAvatarNotImage = errors.New("avatar is not an image")
+
+// UserMeUpdateHandler changes the profile of the current user. A new avatar
+// is promoted like article media, the replaced one goes to the sweeper.
+func (h *Handler) UserMeUpdateHandler(c echo.Context) error {
+	req := c.Get("validatedBody").(*schemas.UserProfileUpdateRequest)
+
+	var user models.User
+	if err := h.DB.Where("id = ?", c.Get("userID").(string)).First(&user).Error; err != nil {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "User not found"})
+	}
+
+	updates := map[string]interface{}{}
+	if req.DisplayName != nil {
+		updates["display_name"] = *req.DisplayName
+	}
+	if req.Bio != nil {
+		updates["bio"] = *req.Bio
+	}
+
+	promoter := h.newObjectPromoter()
+	oldAvatar := user.AvatarKey
+	if req.Avatar != nil {
+		avatarKey := ""
+		if *req.Avatar != "" {
+			avatarKey = extractS3KeyFromPath(*req.Avatar)
+			upload, err := findUpload(h.DB, avatarKey)
+			if err == nil && avatarKey != user.AvatarKey && (upload == nil || upload.UserID != user.ID.String()) {
+				err = errMediaNotOwned
+			}
+			if err == nil && avatarKey != user.AvatarKey {
+				var info utils.MediaInfo
+				info, err = sanitizeObject(h.Storage, avatarKey)
+				if err == nil && (info.Width == 0 || info.Height == 0) {
+					err = errAvatarNotImage
+				}
+			}
+			if err == nil {
+				err = promoter.promote(avatarKey)
+			}
+			if err != nil {
+				if errors.Is(err, errMediaNotOwned) {
+					return c.JSON(http.StatusForbidden, echo.Map{"message": "Media was not uploaded by this user"})
+				}
+				if errors.Is(err, errMediaNotFound) {
+					return c.JSON(http.StatusNotFound, echo.Map{"message": "Media not found"})
+				}
+				if errors.Is(err, errMediaInvalid) {
+					return c.JSON(http.StatusUnprocessableEntity, echo.Map{"message": "Media is not a valid image"})
+				}
+				if errors.Is(err, errAvatarNotImage) {
+					return c.JSON(http.StatusUnprocessableEntity, echo.Map{"message": "Avatar must be an image"})
+				}
+				log.Printf("Error changing status of %v to permanent: %v", avatarKey, err)
+				return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+			}
+		}
+		updates["avatar_key"] = avatarKey
+	}
+
+	if len(updates) > 0 {
+		if err := h.DB.Model(&user).Updates(updates).Error; err != nil {
+			promoter.revert()
+			log.Printf("Error updating profile: %v", err)
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+		}
+		if err := h.DB.Where("id = ?", user.ID).First(&user).Error; err != nil {
+			log.Printf("Error reloading profile: %v", err)
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+		}
+	}
+
+	if oldAvatar != "" && oldAvatar != user.AvatarKey {
+		if _, err := workers.DemoteUnreferenced(h.DB, h.Storage, []string{oldAvatar}); err != nil {
+			log.Printf("Error reconciling replaced avatar: %v", err)
+		}
+	}
+
+	return c.JSON(http.StatusOK, h.userProfileResponse(user))
+}
+
+// UserPageHandler returns the public profile of an author together with a
+// page of their articles, paginated like GET /articles
+func (h *Handler) UserPageHandler(c echo.Context) error {
+	query := c.Get("validatedBody").(*schemas.ArticleListQuery)
+
+	var user models.User
+	if err := h.DB.Where("username = ?", c.Param("username")).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "No such user"})
+		}
+		log.Printf("Error loading user: %v", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	query.Author = user.Username
+	articles, err := h.listArticles(query)
+	var invalid invalidQueryError
+	if errors.As(err, &invalid) {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": invalid.message})
+	}
+	if err != nil {
+		log.Printf("Error listing articles of %v: %v", user.Username, err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": "Internal server error"})
+	}
+
+	return c.JSON(http.StatusOK, schemas.UserPageResponse{
+		Profile:    h.userProfileResponse(user),
+		Articles:   articles.Articles,
+		NextCursor: articles.NextCursor,
+	})
+}
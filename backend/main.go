@@ -6,10 +6,13 @@ import (
 	"rulehub/models"
 	"rulehub/routes"
 	"rulehub/schemas"
+	"rulehub/storage"
 	"rulehub/utils"
+	"rulehub/workers"
 
 	"github.com/go-playground/validator"
 
+	"context"
 	"log"
 	"os"
 
@@ -27,10 +30,18 @@ func main() {
 	}
 
 	db := models.RegisterPostgres()
-	minio, err := utils.CreateMinioClient()
+	store, err := storage.NewFromEnv()
 	if err != nil {
-		log.Fatalf("failed to create MinIO client: %v", err)
-		
+		log.Fatalf("failed to configure storage: %v", err)
+	}
+
+	if err := utils.InitAccessKeyring(); err != nil {
+		log.Fatalf("failed to load JWT keys: %v", err)
+	}
+
+	oidc, err := utils.NewOIDCProviderFromEnv()
+	if err != nil {
+		log.Fatalf("failed to configure OIDC: %v", err)
 	}
 
 	validater := validator.New()
@@ -40,6 +51,13 @@ func main() {
 
 	e.Validator = &middleware.CustomValidator{Validator: validater}
 
+	// Client addresses for rate limits and login throttling
+	ipExtractor, err := middleware.IPExtractorFromEnv()
+	if err != nil {
+		log.Fatalf("failed to configure client IP extraction: %v", err)
+	}
+	e.IPExtractor = ipExtractor
+
 	if os.Getenv("RUNTIME_PRODUCTION") != "true" {
 		e.Use(echoMw.Logger())
 	}
@@ -56,7 +74,20 @@ func main() {
 		return c.JSON(200, schemas.Message{Status: "RuleHUB backend is ok"})
 	})
 
-	handler := &handlers.Handler{DB: db, MinIOClient: minio}
+	sweeper := workers.NewMediaSweeper(db, store)
+	go sweeper.Run(context.Background())
+
+	variants := workers.NewVariantGenerator(db, store)
+	go variants.Run(context.Background())
+
+	handler := &handlers.Handler{
+		DB:            db,
+		Storage:       store,
+		Sweeper:       sweeper,
+		Variants:      variants,
+		LoginThrottle: utils.NewLoginThrottle(),
+		OIDC:          oidc,
+	}
 	routes.RegisterRoutes(e, handler)
 	
 	e.Logger.Fatal(e.Start(":1324"))
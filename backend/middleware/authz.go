@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"rulehub/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Forbidden writes the error body shared by every permission check
+func Forbidden(c echo.Context) error {
+    return c.JSON(http.StatusForbidden, map[string]string{"message": "Forbidden"})
+}
+
+// RequireRole пропускает запрос, только если роль пользователя не ниже minRole.
+// Должен стоять после JWTMiddleware.
+func RequireRole(minRole string) echo.MiddlewareFunc {
+    return func(next echo.HandlerFunc) echo.HandlerFunc {
+        return func(c echo.Context) error {
+            role, _ := c.Get("userRole").(string)
+            if !models.HasRole(role, minRole) {
+                return Forbidden(c)
+            }
+            return next(c)
+        }
+    }
+}
@@ -2,51 +2,130 @@ package middleware
 
 import (
 	"net/http"
-	"os"
+	"strings"
+	"time"
 
+	"rulehub/models"
 	"rulehub/utils"
 
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
-// JWTMiddleware создает middleware для проверки JWT токена
-func JWTMiddleware() echo.MiddlewareFunc {
+// sessionTouchInterval ограничивает частоту обновления last_used_at сессии
+const sessionTouchInterval = time.Minute
+
+// JWTMiddleware создает middleware для проверки JWT токена.
+// Токены отозванных или истекших сессий отклоняются.
+// Персональные токены здесь не принимаются, см. TokenMiddleware.
+func JWTMiddleware(db *gorm.DB) echo.MiddlewareFunc {
     return func(next echo.HandlerFunc) echo.HandlerFunc {
         return func(c echo.Context) error {
-            // Извлекаем токен из заголовка Authorization
-            authHeader := c.Request().Header.Get("Authorization")
-            if authHeader == "" {
-                return c.JSON(http.StatusUnauthorized, map[string]string{"error": "missing token"})
+            tokenString, errMessage := bearerToken(c)
+            if errMessage != "" {
+                return c.JSON(http.StatusUnauthorized, map[string]string{"error": errMessage})
             }
 
-            // Убираем "Bearer " из заголовка
-            tokenString := ""
-            if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-                tokenString = authHeader[7:]
-            } else {
-                return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid token format"})
+            if errMessage := authenticateJWT(c, db, tokenString); errMessage != "" {
+                return c.JSON(http.StatusUnauthorized, map[string]string{"error": errMessage})
             }
 
-            // Получаем секретный ключ через переданную функцию
-            secret := []byte(os.Getenv("PASSWORD_JWT_ACCESS_SECRET"))
+            // Переходим к следующему обработчику
+            return next(c)
+        }
+    }
+}
 
-            // Проверяем токен с помощью функции ValidateToken
-            claims, err := utils.ValidateToken(tokenString, secret)
-            if err != nil {
-                return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid or expired token"})
+// TokenMiddleware принимает как access JWT, так и персональный токен доступа.
+// Персональный токен должен иметь scope маршрута.
+func TokenMiddleware(db *gorm.DB, scope string) echo.MiddlewareFunc {
+    return func(next echo.HandlerFunc) echo.HandlerFunc {
+        return func(c echo.Context) error {
+            tokenString, errMessage := bearerToken(c)
+            if errMessage != "" {
+                return c.JSON(http.StatusUnauthorized, map[string]string{"error": errMessage})
             }
 
-            // Извлекаем user_id из claims
-            userID, ok := claims["user_id"].(string)
-            if !ok {
-                return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid token claims"})
+            if !utils.IsPersonalToken(tokenString) {
+                if errMessage := authenticateJWT(c, db, tokenString); errMessage != "" {
+                    return c.JSON(http.StatusUnauthorized, map[string]string{"error": errMessage})
+                }
+                return next(c)
             }
 
-            // Передаем user_id в контекст
-            c.Set("userID", userID)
+            var token models.AccessToken
+            if err := db.Preload("User").Where("token_hash = ?", utils.HashPersonalToken(tokenString)).First(&token).Error; err != nil || !token.Active() {
+                return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid or expired token"})
+            }
+            if !token.HasScope(scope) {
+                return c.JSON(http.StatusForbidden, map[string]string{"error": "token lacks scope " + scope})
+            }
+            if token.LastUsedAt == nil || time.Since(*token.LastUsedAt) > sessionTouchInterval {
+                db.Model(&token).UpdateColumn("last_used_at", time.Now())
+            }
+
+            // Права определяет текущая роль владельца, а не роль на момент выпуска
+            c.Set("userID", token.UserID)
+            c.Set("userRole", token.User.Role)
+            c.Set("accessTokenID", token.ID.String())
 
-            // Переходим к следующему обработчику
             return next(c)
         }
     }
-}
\ No newline at end of file
+}
+
+// bearerToken извлекает токен из заголовка Authorization
+func bearerToken(c echo.Context) (string, string) {
+    authHeader := c.Request().Header.Get("Authorization")
+    if authHeader == "" {
+        return "", "missing token"
+    }
+
+    // Убираем "Bearer " из заголовка
+    tokenString, ok := strings.CutPrefix(authHeader, "Bearer ")
+    if !ok || tokenString == "" {
+        return "", "invalid token format"
+    }
+    return tokenString, ""
+}
+
+// authenticateJWT проверяет access JWT и заполняет контекст.
+// Возвращает текст ошибки, если токен не принят.
+func authenticateJWT(c echo.Context, db *gorm.DB, tokenString string) string {
+    // Проверяем подпись ключом, указанным в заголовке kid
+    claims, err := utils.ValidateToken(tokenString, utils.AccessKeyring())
+    if err != nil {
+        return "invalid or expired token"
+    }
+
+    // Извлекаем user_id из claims
+    userID, ok := claims["user_id"].(string)
+    if !ok {
+        return "invalid token claims"
+    }
+
+    // Токен действителен, пока жива сессия, в которой он выдан
+    sessionID, ok := claims["sid"].(string)
+    if !ok {
+        return "invalid token claims"
+    }
+    var session models.Session
+    if err := db.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil || !session.Active() {
+        return "session revoked or expired"
+    }
+    if time.Since(session.LastUsedAt) > sessionTouchInterval {
+        db.Model(&session).UpdateColumn("last_used_at", time.Now())
+    }
+
+    // Токены без роли дают только права на чтение
+    role, ok := claims["role"].(string)
+    if !ok {
+        role = models.RoleReader
+    }
+
+    // Передаем user_id, роль и сессию в контекст
+    c.Set("userID", userID)
+    c.Set("userRole", role)
+    c.Set("sessionID", sessionID)
+    return ""
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	echoMw "github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+)
+
+// RateLimit ограничивает частоту запросов с одного IP по алгоритму token bucket:
+// в среднем rps запросов в секунду и не больше burst подряд.
+// Настройки группы name переопределяются переменной RATE_LIMIT_<NAME>="rps,burst",
+// значение "off" отключает ограничение. RATE_LIMIT=off отключает его для всех
+// групп без собственной настройки.
+func RateLimit(name string, rps float64, burst int) echo.MiddlewareFunc {
+    envName := "RATE_LIMIT_" + strings.ToUpper(name)
+    setting := os.Getenv(envName)
+    if setting == "" && os.Getenv("RATE_LIMIT") == "off" {
+        setting = "off"
+    }
+
+    if setting == "off" {
+        return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+    }
+    if setting != "" {
+        parts := strings.Split(setting, ",")
+        parsedRPS, errRPS := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+        parsedBurst, errBurst := 0, error(nil)
+        if len(parts) == 2 {
+            parsedBurst, errBurst = strconv.Atoi(strings.TrimSpace(parts[1]))
+        }
+        if len(parts) != 2 || errRPS != nil || errBurst != nil || parsedRPS <= 0 || parsedBurst <= 0 {
+            log.Printf("Invalid %s=%q, expected \"rps,burst\" or \"off\"; using %v,%v", envName, setting, rps, burst)
+        } else {
+            rps, burst = parsedRPS, parsedBurst
+        }
+    }
+
+    retryAfter := strconv.Itoa(int(math.Ceil(1 / rps)))
+    return echoMw.RateLimiterWithConfig(echoMw.RateLimiterConfig{
+        Skipper: echoMw.DefaultSkipper,
+        Store: echoMw.NewRateLimiterMemoryStoreWithConfig(echoMw.RateLimiterMemoryStoreConfig{
+            Rate:      rate.Limit(rps),
+            Burst:     burst,
+            ExpiresIn: 3 * time.Minute,
+        }),
+        IdentifierExtractor: func(c echo.Context) (string, error) {
+            return c.RealIP(), nil
+        },
+        ErrorHandler: func(c echo.Context, err error) error {
+            return c.JSON(http.StatusForbidden, map[string]string{"message": "Forbidden"})
+        },
+        DenyHandler: func(c echo.Context, identifier string, err error) error {
+            c.Response().Header().Set("Retry-After", retryAfter)
+            return c.JSON(http.StatusTooManyRequests, map[string]string{"message": "Too many requests"})
+        },
+    })
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+    "fmt"
+    "net"
+    "os"
+    "strings"
+
+    "github.com/labstack/echo/v4"
+)
+
+// IPExtractorFromEnv выбирает адрес клиента для ограничения запросов и
+// блокировки входа. За обратным прокси TRUSTED_PROXIES перечисляет через
+// запятую его адреса или подсети, и клиент берется из X-Forwarded-For:
+// записи, добавленные не доверенными прокси, игнорируются. Без настройки
+// используется адрес TCP соединения, заголовкам клиента никто не верит.
+func IPExtractorFromEnv() (echo.IPExtractor, error) {
+    setting := os.Getenv("TRUSTED_PROXIES")
+    if strings.TrimSpace(setting) == "" {
+        return echo.ExtractIPDirect(), nil
+    }
+
+    options := []echo.TrustOption{
+        echo.TrustLoopback(false),
+        echo.TrustLinkLocal(false),
+        echo.TrustPrivateNet(false),
+    }
+    for _, entry := range strings.Split(setting, ",") {
+        entry = strings.TrimSpace(entry)
+        if entry == "" {
+            continue
+        }
+        if !strings.Contains(entry, "/") {
+            if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+                entry += "/32"
+            } else {
+                entry += "/128"
+            }
+        }
+        _, ipRange, err := net.ParseCIDR(entry)
+        if err != nil {
+            return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q: %w", entry, err)
+        }
+        options = append(options, echo.TrustIPRange(ipRange))
+    }
+    return echo.ExtractIPFromXFFHeader(options...), nil
+}
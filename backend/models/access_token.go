@@ -0,0 +1,64 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Scopes a personal access token can be limited to
+const (
+	ScopeArticlesRead  = "articles:read"
+	ScopeArticlesWrite = "articles:write"
+	ScopeMediaRead     = "media:read"
+	ScopeMediaWrite    = "media:write"
+	ScopeAdmin         = "admin"
+)
+
+// scopeRoles is the lowest role that may hold a scope
+var scopeRoles = map[string]string{
+	ScopeArticlesRead:  RoleReader,
+	ScopeArticlesWrite: RoleAuthor,
+	ScopeMediaRead:     RoleReader,
+	ScopeMediaWrite:    RoleAuthor,
+	ScopeAdmin:         RoleAdmin,
+}
+
+// CanGrantScope reports whether a user with the role may create a token with
+// the scope
+func CanGrantScope(role, scope string) bool {
+	minRole, ok := scopeRoles[scope]
+	return ok && HasRole(role, minRole)
+}
+
+// AccessToken is a personal access token for scripts and CI. Only the SHA-256
+// of the token is stored; Prefix is kept to tell tokens apart in listings.
+type AccessToken struct {
+	BaseModel
+	UserID     string     `gorm:"type:uuid;not null;index" json:"user_id"`
+	User       User       `gorm:"foreignKey:UserID" json:"-"`
+	Name       string     `gorm:"type:varchar(64);not null" json:"name"`
+	Prefix     string     `gorm:"type:varchar(16);not null" json:"prefix"`
+	TokenHash  string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	Scopes     string     `gorm:"type:varchar(256);not null" json:"scopes"` // space separated
+	ExpiresAt  time.Time  `gorm:"type:timestamptz;not null" json:"expires_at"`
+	LastUsedAt *time.Time `gorm:"type:timestamptz" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `gorm:"type:timestamptz" json:"revoked_at,omitempty"`
+}
+
+// Active reports whether the token can still be used
+func (t *AccessToken) Active() bool {
+	return t.RevokedAt == nil && time.Now().Before(t.ExpiresAt)
+}
+
+func (t *AccessToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+func (t *AccessToken) HasScope(scope string) bool {
+	for _, s := range t.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -19,9 +19,21 @@ func RegisterPostgres() (*gorm.DB){
 
 	db.Exec(`CREATE EXTENSION IF NOT EXISTS "pgcrypto";`)
 
-	if err := db.AutoMigrate(&User{}, &Article{}, &Media{}); err != nil {
+	if err := db.AutoMigrate(&User{}, &Invite{}, &Article{}, &Media{}, &ArticleRevision{}, &Session{}, &RefreshToken{}, &AccessToken{}, &TwoFactor{}, &RecoveryCode{}, &PasswordReset{}, &UserIdentity{}, &Upload{}, &MediaVariant{}); err != nil {
 		log.Fatalf("failed to migrate database: %v", err)
 	}
 
+	if err := migrateArticleSearch(db); err != nil {
+		log.Fatalf("failed to migrate article search: %v", err)
+	}
+
+	if err := migrateArticleRevisions(db); err != nil {
+		log.Fatalf("failed to backfill article revisions: %v", err)
+	}
+
+	if err := PromoteBootstrapAdmin(db); err != nil {
+		log.Fatalf("failed to promote ADMIN_USERNAME: %v", err)
+	}
+
 	return db
 }
\ No newline at end of file
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// UserIdentity links an account of an external OpenID Connect provider to a
+// user. The provider identifies the account by issuer and subject; the email
+// is only kept for display.
+type UserIdentity struct {
+	BaseModel
+	UserID      string     `gorm:"type:uuid;not null;index" json:"user_id"`
+	User        User       `gorm:"foreignKey:UserID" json:"-"`
+	Issuer      string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_identity_subject" json:"issuer"`
+	Subject     string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_identity_subject" json:"subject"`
+	Email       string     `gorm:"type:varchar(255)" json:"email"`
+	LastLoginAt *time.Time `gorm:"type:timestamptz" json:"last_login_at,omitempty"`
+}
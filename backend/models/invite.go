@@ -0,0 +1,42 @@
+package models
+
+import (
+	"os"
+	"time"
+)
+
+// Registration modes, set with REGISTRATION_MODE
+const (
+	RegistrationOpen   = "open"
+	RegistrationInvite = "invite"
+	RegistrationClosed = "closed"
+)
+
+// RegistrationMode returns the configured mode, open when unset or unknown
+func RegistrationMode() string {
+	switch mode := os.Getenv("REGISTRATION_MODE"); mode {
+	case RegistrationInvite, RegistrationClosed:
+		return mode
+	}
+	return RegistrationOpen
+}
+
+// Invite lets someone register with the given role. Only the SHA-256 of the
+// code is stored; Prefix tells invites apart in listings.
+type Invite struct {
+	BaseModel
+	CodeHash    string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	Prefix      string     `gorm:"type:varchar(16);not null" json:"prefix"`
+	Role        string     `gorm:"type:varchar(16);not null" json:"role"`
+	MaxUses     int        `gorm:"not null;default:1" json:"max_uses"`
+	Uses        int        `gorm:"not null;default:0" json:"uses"`
+	ExpiresAt   time.Time  `gorm:"type:timestamptz;not null" json:"expires_at"`
+	RevokedAt   *time.Time `gorm:"type:timestamptz" json:"revoked_at,omitempty"`
+	CreatedByID string     `gorm:"type:uuid;not null" json:"created_by_id"`
+	CreatedBy   User       `gorm:"foreignKey:CreatedByID" json:"-"`
+}
+
+// Usable reports whether the invite can still be redeemed
+func (i *Invite) Usable() bool {
+	return i.RevokedAt == nil && i.Uses < i.MaxUses && time.Now().Before(i.ExpiresAt)
+}
@@ -6,4 +6,11 @@ type Media struct {
 	S3Key  string `gorm:"type:varchar(256);not null" json:"s3_key"`
 	ArticleID string `gorm:"not null" json:"article_id"`
 	Article   Article `gorm:"foreignKey:ArticleID" json:"article"`
+	// Details of the stored file, after metadata was stripped
+	MimeType  string `gorm:"type:varchar(128)" json:"mime_type"`
+	Size      int64  `json:"size"`
+	Width     int    `json:"width"`  // 0 for files that are not images
+	Height    int    `json:"height"`
+	SHA256    string `gorm:"type:char(64);index" json:"sha256"`
+	Variants  []MediaVariant `gorm:"foreignKey:SourceKey;references:S3Key;constraint:-" json:"-"`
 }
\ No newline at end of file
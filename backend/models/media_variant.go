@@ -0,0 +1,12 @@
+package models
+
+// MediaVariant is a resized copy of a permanent image. Variants belong to the
+// object, not to a media row: articles sharing an object share its variants.
+type MediaVariant struct {
+	BaseModel
+	SourceKey string `gorm:"type:varchar(256);not null;uniqueIndex:idx_media_variant" json:"source_key"`
+	Width     int    `gorm:"not null;uniqueIndex:idx_media_variant" json:"width"`
+	Height    int    `gorm:"not null" json:"height"`
+	Format    string `gorm:"type:varchar(8);not null;uniqueIndex:idx_media_variant" json:"format"` // jpeg or webp
+	Key       string `gorm:"type:varchar(300);not null" json:"key"`
+}
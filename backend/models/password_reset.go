@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// PasswordReset is a one-time token an admin issued so a user can set a new
+// password without knowing the old one. Only the SHA-256 of the token is stored.
+type PasswordReset struct {
+	BaseModel
+	UserID      string     `gorm:"type:uuid;not null;index" json:"user_id"`
+	User        User       `gorm:"foreignKey:UserID" json:"-"`
+	TokenHash   string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	ExpiresAt   time.Time  `gorm:"type:timestamptz;not null" json:"expires_at"`
+	UsedAt      *time.Time `gorm:"type:timestamptz" json:"used_at,omitempty"`
+	CreatedByID string     `gorm:"type:uuid;not null" json:"created_by_id"`
+}
+
+// Usable reports whether the token can still be redeemed
+func (r *PasswordReset) Usable() bool {
+	return r.UsedAt == nil && time.Now().Before(r.ExpiresAt)
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ArticleRevision is an immutable snapshot of an article's text, written on
+// every create and every update that changes the title or content
+type ArticleRevision struct {
+	BaseModel
+	ArticleID    string `gorm:"type:uuid;not null;uniqueIndex:idx_article_revision_number" json:"article_id"`
+	Number       int    `gorm:"not null;uniqueIndex:idx_article_revision_number" json:"number"`
+	Title        string `gorm:"type:varchar(128);not null" json:"title"`
+	Content      string `gorm:"type:text;not null" json:"content"`
+	UserID       string `gorm:"type:uuid;not null" json:"user_id"`
+	User         User   `gorm:"foreignKey:UserID" json:"user"`
+	RestoredFrom *int   `json:"restored_from,omitempty"`
+}
+
+// NextRevisionNumber returns the number the next revision of an article gets.
+// It locks the article row, so db must be the transaction that creates the
+// revision; concurrent edits then wait instead of taking the same number.
+func NextRevisionNumber(db *gorm.DB, articleID string) (int, error) {
+	var article Article
+	if err := db.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+		Where("id = ?", articleID).Take(&article).Error; err != nil {
+		return 0, err
+	}
+	var last int
+	err := db.Model(&ArticleRevision{}).Where("article_id = ?", articleID).
+		Select("COALESCE(MAX(number), 0)").Scan(&last).Error
+	return last + 1, err
+}
+
+// migrateArticleRevisions gives articles created before revisions existed
+// their current state as revision 1
+func migrateArticleRevisions(db *gorm.DB) error {
+	return db.Exec(`INSERT INTO article_revisions (article_id, number, title, content, user_id, created_at, updated_at)
+		SELECT a.id, 1, a.title, a.content, a.user_id, a.updated_at, a.updated_at
+		FROM articles a
+		WHERE NOT EXISTS (SELECT 1 FROM article_revisions r WHERE r.article_id = a.id)`).Error
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"errors"
+	"log"
+	"os"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	RoleReader = "reader"
+	RoleAuthor = "author"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// roleRanks orders roles from least to most privileged
+var roleRanks = map[string]int{
+	RoleReader: 1,
+	RoleAuthor: 2,
+	RoleEditor: 3,
+	RoleAdmin:  4,
+}
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// HasRole reports whether role grants at least the permissions of minRole
+func HasRole(role, minRole string) bool {
+	return roleRanks[role] > 0 && roleRanks[role] >= roleRanks[minRole]
+}
+
+// CanModifyArticle reports whether the caller may change or delete an article.
+// Editors and admins may change any article, authors only their own.
+func CanModifyArticle(userID, role string, article *Article) bool {
+	if HasRole(role, RoleEditor) {
+		return true
+	}
+	return HasRole(role, RoleAuthor) && article.UserID == userID
+}
+
+// firstUserLock is the advisory lock that serializes creating the first
+// account, so that two concurrent registrations can not both become admin
+const firstUserLock = 0x72756c65 // "rule"
+
+// LockFirstUser takes the first account lock for the rest of tx and reports
+// whether no account exists yet. Call it in the transaction that creates
+// the user.
+func LockFirstUser(tx *gorm.DB) (bool, error) {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", firstUserLock).Error; err != nil {
+		return false, err
+	}
+	var count int64
+	if err := tx.Model(&User{}).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// LockAdmins locks the rows of all admins for the rest of tx and returns
+// how many there are. Changes that can take away the last admin call it
+// first, so that two of them can not pass the check at once.
+func LockAdmins(tx *gorm.DB) (int, error) {
+	var ids []string
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&User{}).
+		Where("role = ?", RoleAdmin).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// PromoteBootstrapAdmin makes the account named by ADMIN_USERNAME an admin.
+// Only the first account of a fresh database becomes admin on its own, this
+// is how deployments that had users before roles existed get one.
+func PromoteBootstrapAdmin(db *gorm.DB) error {
+	username := os.Getenv("ADMIN_USERNAME")
+	if username == "" {
+		var admins int64
+		if err := db.Model(&User{}).Where("role = ?", RoleAdmin).Count(&admins).Error; err != nil {
+			return err
+		}
+		var users int64
+		if err := db.Model(&User{}).Count(&users).Error; err != nil {
+			return err
+		}
+		if admins == 0 && users > 0 {
+			log.Printf("No admin account exists, set ADMIN_USERNAME to promote one")
+		}
+		return nil
+	}
+
+	result := db.Model(&User{}).Where("username = ? AND role <> ?", username, RoleAdmin).Update("role", RoleAdmin)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Promoted %s to admin (ADMIN_USERNAME)", username)
+	} else if err := db.Where("username = ?", username).First(&User{}).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("ADMIN_USERNAME %s does not exist, nobody was promoted", username)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package models
+
+import "gorm.io/gorm"
+
+// SearchConfig is the text search configuration used for articles. It has no
+// stemming, so it behaves the same for russian and english rules.
+const SearchConfig = "simple"
+
+// migrateArticleSearch adds the generated tsvector column with its GIN index.
+// Adding a stored generated column rewrites the table, so existing articles
+// are indexed as part of the migration.
+func migrateArticleSearch(db *gorm.DB) error {
+	if err := db.Exec(`ALTER TABLE articles ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('` + SearchConfig + `', coalesce(title, '')), 'A') ||
+			setweight(to_tsvector('` + SearchConfig + `', coalesce(content, '')), 'B')
+		) STORED`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`CREATE INDEX IF NOT EXISTS idx_articles_search_vector ON articles USING GIN (search_vector)`).Error
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Session is one login of a user on a device. Every refresh token issued for
+// it belongs to the same family; reusing a rotated token revokes the session.
+type Session struct {
+	BaseModel
+	UserID     string     `gorm:"type:uuid;not null;index" json:"user_id"`
+	User       User       `gorm:"foreignKey:UserID" json:"-"`
+	UserAgent  string     `gorm:"type:varchar(256)" json:"user_agent"`
+	IP         string     `gorm:"type:varchar(64)" json:"ip"`
+	LastUsedAt time.Time  `gorm:"type:timestamptz;not null" json:"last_used_at"`
+	ExpiresAt  time.Time  `gorm:"type:timestamptz;not null" json:"expires_at"`
+	RevokedAt  *time.Time `gorm:"type:timestamptz" json:"revoked_at,omitempty"`
+}
+
+// Active reports whether the session can still be used
+func (s *Session) Active() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// RefreshToken records an issued refresh JWT by its jti
+type RefreshToken struct {
+	JTI       string     `gorm:"type:uuid;primaryKey" json:"jti"`
+	SessionID string     `gorm:"type:uuid;not null;index" json:"session_id"`
+	Session   Session    `gorm:"foreignKey:SessionID" json:"-"`
+	CreatedAt time.Time  `gorm:"type:timestamptz;default:now()" json:"created_at"`
+	ExpiresAt time.Time  `gorm:"type:timestamptz;not null" json:"expires_at"`
+	RotatedAt *time.Time `gorm:"type:timestamptz" json:"rotated_at,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// TwoFactor holds the TOTP secret of a user. The row is created on
+// enrollment and the second factor is only required once EnabledAt is set.
+type TwoFactor struct {
+	UserID      string     `gorm:"type:uuid;primaryKey" json:"user_id"`
+	User        User       `gorm:"foreignKey:UserID" json:"-"`
+	Secret      string     `gorm:"type:varchar(64);not null" json:"-"`
+	EnabledAt   *time.Time `gorm:"type:timestamptz" json:"enabled_at,omitempty"`
+	LastCounter int64      `gorm:"not null;default:0" json:"-"` // last accepted TOTP time step, blocks replays
+	CreatedAt   time.Time  `gorm:"type:timestamptz;default:now()" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"type:timestamptz;default:now()" json:"updated_at"`
+}
+
+func (t *TwoFactor) Enabled() bool {
+	return t.EnabledAt != nil
+}
+
+// RecoveryCode is a single-use replacement for a TOTP code, stored hashed
+type RecoveryCode struct {
+	BaseModel
+	UserID   string     `gorm:"type:uuid;not null;index" json:"user_id"`
+	User     User       `gorm:"foreignKey:UserID" json:"-"`
+	CodeHash string     `gorm:"type:varchar(255);not null;index" json:"-"` // sha256 of the normalized code
+	UsedAt   *time.Time `gorm:"type:timestamptz" json:"used_at,omitempty"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Upload records a presigned upload issued by /media/upload-temp. Only the
+// user an upload was issued to may attach its object.
+type Upload struct {
+	BaseModel
+	FileID      string    `gorm:"type:varchar(256);not null;uniqueIndex" json:"file_id"` // object key
+	UserID      string    `gorm:"type:uuid;not null;index" json:"user_id"`
+	User        User      `gorm:"foreignKey:UserID" json:"-"`
+	FileName    string    `gorm:"type:varchar(128);not null" json:"file_name"` // as declared by the client
+	ContentType string    `gorm:"type:varchar(128);not null" json:"content_type"`
+	ExpiresAt   time.Time `gorm:"type:timestamptz;not null" json:"expires_at"` // end of the upload window
+}
@@ -3,5 +3,11 @@ package models
 type User struct {
 	BaseModel
 	Username string `gorm:"type:varchar(32);unique;not null" json:"username"`
-	Password string `gorm:"type:varchar(45);not null" json:"password"`
-}
\ No newline at end of file
+	Password string `gorm:"type:varchar(255);not null" json:"password"` // PHC string, see utils.HashPassword
+	Role     string `gorm:"type:varchar(16);not null;default:'author'" json:"role"`
+	InviteID *string `gorm:"type:uuid" json:"invite_id,omitempty"` // invite used to register, if any
+
+	DisplayName string `gorm:"type:varchar(64);not null;default:''" json:"display_name"`
+	Bio         string `gorm:"type:varchar(1024);not null;default:''" json:"bio"`
+	AvatarKey   string `gorm:"type:varchar(256);not null;default:''" json:"avatar_key"` // S3 key of a permanent object, empty for none
+}
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"rulehub/handlers"
+	"rulehub/middleware"
+	"rulehub/models"
+	"rulehub/schemas"
+
+	"github.com/labstack/echo/v4"
+)
+
+func RegisterAdminRoutes(e *echo.Echo, h *handlers.Handler) {
+	group := e.Group("/admin", middleware.RateLimit("admin", 10, 30), middleware.TokenMiddleware(h.DB, models.ScopeAdmin), middleware.RequireRole(models.RoleAdmin))
+
+	group.PUT("/users/:username/role", h.AdminSetUserRoleHandler, middleware.ValidationMiddleware(func() interface{} {
+		return &schemas.UserRoleUpdateRequest{}
+	}))
+
+	group.POST("/users/:username/password-reset", h.AdminPasswordResetCreateHandler)
+
+	group.POST("/invites", h.AdminInviteCreateHandler, middleware.ValidationMiddleware(func() interface{} {
+		return &schemas.InviteCreateRequest{}
+	}))
+	group.GET("/invites", h.AdminInviteListHandler)
+	group.DELETE("/invites/:id", h.AdminInviteRevokeHandler)
+
+	group.GET("/media/sweeper", h.AdminSweeperStatusHandler)
+	group.POST("/media/sweeper/run", h.AdminSweeperRunHandler)
+	group.POST("/media/reconcile", h.AdminMediaReconcileHandler, middleware.ValidationMiddleware(func() interface{} {
+		return &schemas.MediaReconcileRequest{}
+	}))
+}
@@ -3,20 +3,43 @@ package routes
 import (
 	"rulehub/handlers"
 	"rulehub/middleware"
+	"rulehub/models"
 	"rulehub/schemas"
 
 	"github.com/labstack/echo/v4"
 )
 
 func RegisterArticleRoutes(e *echo.Echo, h* handlers.Handler) {
-	group := e.Group("/articles")
+	group := e.Group("/articles", middleware.RateLimit("articles", 20, 60))
 
 	group.POST("/", h.ArticleCreateHandler, middleware.ValidationMiddleware(func() interface{} {
 		return &schemas.ArticleCreateRequest{}
-	}), middleware.JWTMiddleware())
+	}), middleware.TokenMiddleware(h.DB, models.ScopeArticlesWrite), middleware.RequireRole(models.RoleAuthor))
+
+	listQuery := middleware.ValidationMiddleware(func() interface{} {
+		return &schemas.ArticleListQuery{}
+	})
+	group.GET("", h.ArticleListHandler, listQuery)
+	group.GET("/", h.ArticleListHandler, listQuery)
+
+	group.GET("/search", h.ArticleSearchHandler, middleware.ValidationMiddleware(func() interface{} {
+		return &schemas.ArticleSearchQuery{}
+	}))
+
+	group.GET("/trash", h.ArticleTrashListHandler, middleware.TokenMiddleware(h.DB, models.ScopeArticlesRead))
 
 	group.GET("/:uuid", h.ArticleGetHandler)
 	group.PUT("/:uuid", h.ArticleChangeHandler, middleware.ValidationMiddleware(func() interface{} {
 		return &schemas.ArticleUpdateRequest{}
-	}), middleware.JWTMiddleware())
+	}), middleware.TokenMiddleware(h.DB, models.ScopeArticlesWrite))
+	group.DELETE("/:uuid", h.ArticleDeleteHandler, middleware.TokenMiddleware(h.DB, models.ScopeArticlesWrite))
+	group.POST("/:uuid/restore", h.ArticleRestoreHandler, middleware.TokenMiddleware(h.DB, models.ScopeArticlesWrite))
+	group.DELETE("/:uuid/purge", h.ArticlePurgeHandler, middleware.TokenMiddleware(h.DB, models.ScopeArticlesWrite))
+
+	group.GET("/:uuid/revisions", h.ArticleRevisionListHandler)
+	group.GET("/:uuid/revisions/diff", h.ArticleRevisionDiffHandler, middleware.ValidationMiddleware(func() interface{} {
+		return &schemas.RevisionDiffQuery{}
+	}))
+	group.GET("/:uuid/revisions/:number", h.ArticleRevisionGetHandler)
+	group.POST("/:uuid/revisions/:number/restore", h.ArticleRevisionRestoreHandler, middleware.TokenMiddleware(h.DB, models.ScopeArticlesWrite))
 }
\ No newline at end of file
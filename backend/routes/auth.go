@@ -9,15 +9,57 @@ import (
 )
 
 func RegisterAuthRoutes(e *echo.Echo, h* handlers.Handler) {
-	group := e.Group("/auth")
+	group := e.Group("/auth", middleware.RateLimit("auth", 2, 20))
 
 	group.POST("/login", h.UserLoginHandler, middleware.ValidationMiddleware(func() interface{} {
 		return &schemas.SignInRequest{}
 	}))
 
+	group.POST("/login/2fa", h.UserLoginTwoFactorHandler, middleware.ValidationMiddleware(func() interface{} {
+		return &schemas.TwoFactorLoginRequest{}
+	}))
+
 	group.POST("/register", h.UserRegistrationHandler, middleware.ValidationMiddleware(func() interface{} {
 		return &schemas.SignUpRequest{}
 	}))
 
 	group.POST("/refresh", h.UserJwtRefreshHandler)
-}
\ No newline at end of file
+	group.POST("/logout", h.UserLogoutHandler)
+	group.POST("/logout-all", h.UserLogoutAllHandler, middleware.JWTMiddleware(h.DB))
+
+	group.POST("/password", h.UserPasswordChangeHandler, middleware.ValidationMiddleware(func() interface{} {
+		return &schemas.PasswordChangeRequest{}
+	}), middleware.JWTMiddleware(h.DB))
+	group.POST("/reset", h.UserPasswordResetHandler, middleware.ValidationMiddleware(func() interface{} {
+		return &schemas.PasswordResetRequest{}
+	}))
+
+	group.GET("/sessions", h.UserSessionListHandler, middleware.JWTMiddleware(h.DB))
+	group.DELETE("/sessions/:id", h.UserSessionRevokeHandler, middleware.JWTMiddleware(h.DB))
+
+	group.POST("/tokens", h.AccessTokenCreateHandler, middleware.ValidationMiddleware(func() interface{} {
+		return &schemas.AccessTokenCreateRequest{}
+	}), middleware.JWTMiddleware(h.DB))
+	group.GET("/tokens", h.AccessTokenListHandler, middleware.JWTMiddleware(h.DB))
+	group.DELETE("/tokens/:id", h.AccessTokenRevokeHandler, middleware.JWTMiddleware(h.DB))
+
+	oidc := group.Group("/oidc")
+	oidc.GET("/login", h.OIDCLoginHandler)
+	oidc.GET("/callback", h.OIDCCallbackHandler)
+	oidc.POST("/link", h.OIDCLinkHandler, middleware.JWTMiddleware(h.DB))
+	oidc.GET("/identities", h.UserIdentityListHandler, middleware.JWTMiddleware(h.DB))
+	oidc.DELETE("/identities/:id", h.UserIdentityUnlinkHandler, middleware.JWTMiddleware(h.DB))
+
+	twoFactor := group.Group("/2fa", middleware.JWTMiddleware(h.DB))
+	twoFactor.GET("", h.TwoFactorStatusHandler)
+	twoFactor.POST("/enroll", h.TwoFactorEnrollHandler)
+	twoFactor.POST("/confirm", h.TwoFactorConfirmHandler, middleware.ValidationMiddleware(func() interface{} {
+		return &schemas.TwoFactorCodeRequest{}
+	}))
+	twoFactor.POST("/disable", h.TwoFactorDisableHandler, middleware.ValidationMiddleware(func() interface{} {
+		return &schemas.TwoFactorDisableRequest{}
+	}))
+	twoFactor.POST("/recovery-codes", h.TwoFactorRecoveryCodesHandler, middleware.ValidationMiddleware(func() interface{} {
+		return &schemas.TwoFactorCodeRequest{}
+	}))
+}
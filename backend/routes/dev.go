@@ -10,4 +10,6 @@ func RegisterDevRoutes(e *echo.Echo, h *handlers.Handler) {
 	group := e.Group("/dev")
 
 	group.POST("/reset-db", h.DropDB)
+	group.PUT("/users/:username/legacy-password", h.DevSetLegacyPassword)
+	group.GET("/users/:username/password-format", h.DevPasswordFormat)
 }
\ No newline at end of file
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"rulehub/handlers"
+	"rulehub/middleware"
+	"rulehub/storage"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RegisterFileRoutes serves uploads and downloads of the local storage. With
+// MinIO clients talk to the bucket directly and no routes are registered.
+func RegisterFileRoutes(e *echo.Echo, h *handlers.Handler) {
+	if _, ok := h.Storage.(*storage.Local); !ok {
+		return
+	}
+	group := e.Group("/files")
+
+	group.GET("/*", h.FileGetHandler)
+	group.POST("", h.FileUploadHandler, middleware.RateLimit("files", 10, 30))
+}
@@ -3,13 +3,17 @@ package routes
 import (
 	"rulehub/handlers"
 	"rulehub/middleware"
+	"rulehub/models"
+	"rulehub/schemas"
 
 	"github.com/labstack/echo/v4"
 )
 
 func RegisterMediaRoutes(e *echo.Echo, h* handlers.Handler) {
-	group := e.Group("/media")
+	group := e.Group("/media", middleware.RateLimit("media", 10, 30))
 
-	group.POST("/upload-temp", h.MediaUploadTempHandler, middleware.JWTMiddleware())
-	group.GET("/gen_static_get", h.MediaGetURLHandler, middleware.JWTMiddleware())
+	group.POST("/upload-temp", h.MediaUploadTempHandler, middleware.ValidationMiddleware(func() interface{} {
+		return &schemas.MediaUploadRequest{}
+	}), middleware.TokenMiddleware(h.DB, models.ScopeMediaWrite), middleware.RequireRole(models.RoleAuthor))
+	group.GET("/gen_static_get", h.MediaGetURLHandler, middleware.TokenMiddleware(h.DB, models.ScopeMediaRead))
 }
\ No newline at end of file
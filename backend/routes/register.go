@@ -13,6 +13,10 @@ func RegisterRoutes(e *echo.Echo, h *handlers.Handler) {
 	RegisterAuthRoutes(e, h)
 	RegisterArticleRoutes(e, h)
 	RegisterMediaRoutes(e, h)
+	RegisterFileRoutes(e, h)
+	RegisterUserRoutes(e, h)
+	RegisterAdminRoutes(e, h)
+	RegisterWellKnownRoutes(e, h)
 
 	if os.Getenv("RUNTIME_PRODUCTION") != "true" || os.Getenv("TEST_ENV") == "true" {
 		log.Println("Registering debug endpoints (development mode)")
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"rulehub/handlers"
+	"rulehub/middleware"
+	"rulehub/schemas"
+
+	"github.com/labstack/echo/v4"
+)
+
+func RegisterUserRoutes(e *echo.Echo, h* handlers.Handler) {
+	group := e.Group("/users", middleware.RateLimit("users", 20, 60))
+
+	group.GET("/me", h.UserMeGetHandler, middleware.JWTMiddleware(h.DB))
+	group.PUT("/me", h.UserMeUpdateHandler, middleware.ValidationMiddleware(func() interface{} {
+		return &schemas.UserProfileUpdateRequest{}
+	}), middleware.JWTMiddleware(h.DB))
+	group.DELETE("/me", h.UserDeleteHandler, middleware.ValidationMiddleware(func() interface{} {
+		return &schemas.AccountDeleteRequest{}
+	}), middleware.JWTMiddleware(h.DB))
+	group.GET("/me/export", h.UserExportHandler, middleware.JWTMiddleware(h.DB))
+
+	group.GET("/:username", h.UserPageHandler, middleware.ValidationMiddleware(func() interface{} {
+		return &schemas.ArticleListQuery{}
+	}))
+}
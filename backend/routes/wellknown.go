@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"rulehub/handlers"
+	"rulehub/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func RegisterWellKnownRoutes(e *echo.Echo, h *handlers.Handler) {
+	group := e.Group("/.well-known", middleware.RateLimit("wellknown", 10, 30))
+
+	group.GET("/jwks.json", h.JWKSHandler)
+}
@@ -0,0 +1,29 @@
+package schemas
+
+import "time"
+
+type AccessTokenCreateRequest struct {
+	Name          string   `json:"name" validate:"required,min=1,max=64"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=articles:read articles:write media:read media:write admin"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+}
+
+type AccessTokenResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// AccessTokenCreateResponse is the only time the token itself is returned
+type AccessTokenCreateResponse struct {
+	AccessTokenResponse
+	Token string `json:"token"`
+}
+
+type AccessTokenListResponse struct {
+	Tokens []AccessTokenResponse `json:"tokens"`
+}
@@ -0,0 +1,11 @@
+package schemas
+
+type UserRoleUpdateRequest struct {
+	Role string `json:"role" validate:"required,oneof=reader author editor admin"`
+}
+
+type UserRoleResponse struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
@@ -1,5 +1,7 @@
 package schemas
 
+import "time"
+
 type ArticleCreateRequest struct {
 	Title       string `json:"title" validate:"required,min=3,max=128"`
 	Content     string `json:"content" validate:"required,min=1,max=10000"`
@@ -9,6 +11,13 @@ type ArticleCreateRequest struct {
 type MediaCreateResponse struct {
 	FileName string `json:"file_name"`
 	S3Key    string `json:"s3_key"`
+	MimeType string `json:"mime_type,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	Width    int    `json:"width,omitempty"` // images only
+	Height   int    `json:"height,omitempty"`
+	SHA256   string `json:"sha256,omitempty"`
+	// Resized copies of images by "<format>_<width>", e.g. "webp_800"
+	Variants map[string]string `json:"variants,omitempty"`
 }
 
 type ArticleResponse struct {
@@ -17,10 +26,52 @@ type ArticleResponse struct {
 	Content        string              `json:"content"`
 	MediaPresignedUrl          []MediaCreateResponse `json:"media"`
 	AuthorUsername string              `json:"author"`
+	AuthorProfile  AuthorSummary       `json:"author_profile"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+	DeletedAt      *time.Time          `json:"deleted_at,omitempty"`
 }
 
 type ArticleUpdateRequest struct {
 	Title   *string   `json:"title" validate:"omitempty,min=3,max=128"`
 	Content *string   `json:"content" validate:"omitempty,min=1,max=10000"`
 	Media   *[]string `json:"media" validate:"omitempty,dive,min=1,max=128"`
-}
\ No newline at end of file
+}
+// ArticleListQuery is bound from the query string of GET /articles
+type ArticleListQuery struct {
+	Cursor string `query:"cursor" validate:"omitempty,max=512"`
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Sort   string `query:"sort" validate:"omitempty,oneof=created updated title"`
+	Order  string `query:"order" validate:"omitempty,oneof=asc desc"`
+	Author string `query:"author" validate:"omitempty,min=3,max=32"`
+	From   string `query:"from" validate:"omitempty,max=64"` // RFC3339, inclusive
+	To     string `query:"to" validate:"omitempty,max=64"`   // RFC3339, exclusive
+}
+
+type ArticleListResponse struct {
+	Articles   []ArticleResponse `json:"articles"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// ArticleSearchQuery is bound from the query string of GET /articles/search.
+// q supports "quoted phrases" and prefix* matches.
+type ArticleSearchQuery struct {
+	Q      string `query:"q" validate:"required,min=1,max=256"`
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Offset int    `query:"offset" validate:"omitempty,min=0,max=10000"`
+}
+
+type ArticleSearchResult struct {
+	ID             string    `json:"id"`
+	Title          string    `json:"title"`
+	TitleHighlight string    `json:"title_highlight"`
+	Snippet        string    `json:"snippet"`
+	Rank           float64   `json:"rank"`
+	AuthorUsername string    `json:"author"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type ArticleSearchResponse struct {
+	Results []ArticleSearchResult `json:"results"`
+}
@@ -1,19 +1,26 @@
 package schemas
 
+import "rulehub/utils"
+
 type SignInRequest struct {
 	Username string `json:"username" validate:"required,min=3,max=32,validusername"`
-	Password string `json:"password" validate:"required,min=6,max=128,strongpwd"`
+	Password string `json:"password" validate:"required,min=6,max=128,strongpwd" redact:"true"`
 }
 
+func (r SignInRequest) String() string { return utils.Redact(r) }
+
 type SignInResponse struct {
 	AccessJWT string `json:"access_token"`
 }
 
 type SignUpRequest struct {
-	Username string `json:"username" validate:"required,min=3,max=32,validusername"`
-	Password string `json:"password" validate:"required,min=6,max=128,strongpwd"`
+	Username   string `json:"username" validate:"required,min=3,max=32,validusername"`
+	Password   string `json:"password" validate:"required,min=6,max=128,strongpwd" redact:"true"`
+	InviteCode string `json:"invite_code" validate:"omitempty,max=64" redact:"true"`
 }
 
+func (r SignUpRequest) String() string { return utils.Redact(r) }
+
 type SignUpResponse struct {
 	ID	     string `json:"id"`
 	Username string `json:"username"`
@@ -0,0 +1,31 @@
+package schemas
+
+import "time"
+
+type InviteCreateRequest struct {
+	Role           string `json:"role" validate:"required,oneof=reader author editor admin"`
+	MaxUses        int    `json:"max_uses" validate:"omitempty,min=1,max=1000"`
+	ExpiresInHours int    `json:"expires_in_hours" validate:"omitempty,min=1,max=8760"`
+}
+
+type InviteResponse struct {
+	ID        string     `json:"id"`
+	Prefix    string     `json:"prefix"`
+	Role      string     `json:"role"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// InviteCreateResponse is the only time the invite code itself is returned
+type InviteCreateResponse struct {
+	InviteResponse
+	Code string `json:"code"`
+}
+
+type InviteListResponse struct {
+	Invites []InviteResponse `json:"invites"`
+}
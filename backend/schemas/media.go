@@ -1,7 +1,20 @@
 package schemas
 
-// MediaUploadResponse is the response schema for generating temporary upload URLs
+import "time"
+
+// MediaUploadRequest declares the file a client is about to upload
+type MediaUploadRequest struct {
+	FileName    string `json:"file_name" validate:"required,min=1,max=128"`
+	ContentType string `json:"content_type" validate:"required,max=128"`
+	Size        int64  `json:"size" validate:"required,min=1"`
+}
+
+// MediaUploadResponse is the response schema for generating temporary upload URLs.
+// The file is sent as multipart/form-data POST to TempURL with every field of
+// FormData followed by the file itself in the "file" field.
 type MediaUploadResponse struct {
-	TempURL string `json:"temp_url"`
-	FileID  string `json:"file_id"`
+	TempURL   string            `json:"temp_url"`
+	FormData  map[string]string `json:"form_data"`
+	FileID    string            `json:"file_id"`
+	ExpiresAt time.Time         `json:"expires_at"`
 }
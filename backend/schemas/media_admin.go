@@ -0,0 +1,45 @@
+package schemas
+
+import "time"
+
+// SweepStats describes one pass of the temporary upload sweeper
+type SweepStats struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Scanned    int       `json:"scanned"`
+	Deleted    int       `json:"deleted"`
+	Kept       int       `json:"kept"`
+	Errors     int       `json:"errors"`
+}
+
+type SweeperStatus struct {
+	TTLSeconds      int         `json:"ttl_seconds"`
+	IntervalSeconds int         `json:"interval_seconds"`
+	Running         bool        `json:"running"`
+	Runs            int         `json:"runs"`
+	TotalDeleted    int         `json:"total_deleted"`
+	LastRun         *SweepStats `json:"last_run,omitempty"`
+}
+
+// MediaReconcileRequest selects what the reconciliation job does with permanent
+// objects that no article references: demote them to temporary so the sweeper
+// collects them, delete them right away, or only report them
+type MediaReconcileRequest struct {
+	Mode string `json:"mode" validate:"omitempty,oneof=demote delete report"`
+}
+
+type DanglingMedia struct {
+	MediaID   string `json:"media_id"`
+	ArticleID string `json:"article_id"`
+	S3Key     string `json:"s3_key"`
+}
+
+type MediaReconcileReport struct {
+	Mode           string          `json:"mode"`
+	ScannedObjects int             `json:"scanned_objects"`
+	Unreferenced   []string        `json:"unreferenced"`
+	Demoted        int             `json:"demoted"`
+	Deleted        int             `json:"deleted"`
+	Errors         int             `json:"errors"`
+	DanglingRows   []DanglingMedia `json:"dangling_rows"`
+}
@@ -0,0 +1,22 @@
+package schemas
+
+import "time"
+
+// OIDCLinkResponse carries the provider URL the browser has to open to link
+// an identity to the current user
+type OIDCLinkResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+type IdentityResponse struct {
+	ID          string     `json:"id"`
+	Issuer      string     `json:"issuer"`
+	Subject     string     `json:"subject"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+type IdentityListResponse struct {
+	Identities []IdentityResponse `json:"identities"`
+}
@@ -0,0 +1,29 @@
+package schemas
+
+import (
+	"time"
+
+	"rulehub/utils"
+)
+
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required,max=128" redact:"true"`
+	NewPassword     string `json:"new_password" validate:"required,min=6,max=128,strongpwd" redact:"true"`
+}
+
+func (r PasswordChangeRequest) String() string { return utils.Redact(r) }
+
+// PasswordResetCreateResponse is the only time the reset token itself is
+// returned; the admin passes it on to the user
+type PasswordResetCreateResponse struct {
+	Username  string    `json:"username"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type PasswordResetRequest struct {
+	Token       string `json:"token" validate:"required,max=64" redact:"true"`
+	NewPassword string `json:"new_password" validate:"required,min=6,max=128,strongpwd" redact:"true"`
+}
+
+func (r PasswordResetRequest) String() string { return utils.Redact(r) }
@@ -0,0 +1,32 @@
+package schemas
+
+import "time"
+
+type RevisionSummary struct {
+	Number         int       `json:"number"`
+	Title          string    `json:"title"`
+	AuthorUsername string    `json:"author"`
+	RestoredFrom   *int      `json:"restored_from,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type RevisionResponse struct {
+	RevisionSummary
+	Content string `json:"content"`
+}
+
+type RevisionListResponse struct {
+	Revisions []RevisionSummary `json:"revisions"`
+}
+
+// RevisionDiffQuery is bound from the query string of the diff endpoint
+type RevisionDiffQuery struct {
+	From int `query:"from" validate:"required,min=1"`
+	To   int `query:"to" validate:"required,min=1"`
+}
+
+type RevisionDiffResponse struct {
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Diff string `json:"diff"`
+}
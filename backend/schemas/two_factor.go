@@ -0,0 +1,51 @@
+package schemas
+
+import (
+	"time"
+
+	"rulehub/utils"
+)
+
+// TwoFactorChallengeResponse is returned by /auth/login instead of tokens when
+// the account has two-factor authentication enabled
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
+// TwoFactorLoginRequest completes a two-step login. Code is a TOTP code or a
+// recovery code.
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required" redact:"true"`
+	Code           string `json:"code" validate:"required,min=6,max=32" redact:"true"`
+}
+
+func (r TwoFactorLoginRequest) String() string { return utils.Redact(r) }
+
+type TwoFactorEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric" redact:"true"`
+}
+
+func (r TwoFactorCodeRequest) String() string { return utils.Redact(r) }
+
+type TwoFactorDisableRequest struct {
+	Password string `json:"password" validate:"required,max=128" redact:"true"`
+	Code     string `json:"code" validate:"required,min=6,max=32" redact:"true"`
+}
+
+func (r TwoFactorDisableRequest) String() string { return utils.Redact(r) }
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorStatusResponse struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesLeft int64      `json:"recovery_codes_left"`
+}
@@ -0,0 +1,46 @@
+package schemas
+
+import (
+	"time"
+
+	"rulehub/utils"
+)
+
+// AuthorSummary is the part of a profile shown next to an article
+type AuthorSummary struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+}
+
+type UserProfileResponse struct {
+	AuthorSummary
+	Bio       string    `json:"bio"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserProfileUpdateRequest changes only the fields that are present. Avatar
+// is a file uploaded through /media/upload-temp, an empty string removes it.
+type UserProfileUpdateRequest struct {
+	DisplayName *string `json:"display_name" validate:"omitempty,max=64"`
+	Bio         *string `json:"bio" validate:"omitempty,max=1024"`
+	Avatar      *string `json:"avatar" validate:"omitempty,max=256"`
+}
+
+// UserPageResponse is the public page of an author
+type UserPageResponse struct {
+	Profile    UserProfileResponse `json:"profile"`
+	Articles   []ArticleResponse   `json:"articles"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// AccountDeleteRequest removes the current account. Articles are either moved
+// to ReassignTo or to the trash; Password is required when the account has one.
+type AccountDeleteRequest struct {
+	Password   string `json:"password" validate:"max=128" redact:"true"`
+	Articles   string `json:"articles" validate:"required,oneof=reassign delete"`
+	ReassignTo string `json:"reassign_to" validate:"omitempty,min=3,max=32"`
+}
+
+func (r AccountDeleteRequest) String() string { return utils.Redact(r) }
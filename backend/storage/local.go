@@ -0,0 +1,350 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidUpload is returned for upload forms with a wrong signature,
+	// a changed field or an expired policy
+	ErrInvalidUpload = errors.New("invalid upload policy")
+	// ErrUploadSize is returned when an uploaded file is empty or larger
+	// than its policy allows
+	ErrUploadSize = errors.New("upload size out of range")
+)
+
+// Local keeps objects as files on disk and serves them from the backend, for
+// deployments without MinIO. Objects live in Dir/files, their content type
+// and status in Dir/meta. Uploads are POSTed to the backend with form fields
+// signed by Secret, like S3 POST policies.
+type Local struct {
+	Dir     string
+	BaseURL string // public URL of the backend, files are served under /files
+	Secret  []byte
+}
+
+type localMeta struct {
+	ContentType string `json:"content_type"`
+	Status      string `json:"status,omitempty"`
+}
+
+// NewLocalFromEnv stores files in STORAGE_LOCAL_DIR (./data/media by default)
+// and builds URLs from STORAGE_LOCAL_URL. Upload forms are signed with
+// STORAGE_LOCAL_SECRET, which production (RUNTIME_PRODUCTION=true) requires;
+// elsewhere a random key is used and forms issued before a restart stop
+// working.
+func NewLocalFromEnv() (*Local, error) {
+	dir := os.Getenv("STORAGE_LOCAL_DIR")
+	if dir == "" {
+		dir = "./data/media"
+	}
+
+	secret := []byte(os.Getenv("STORAGE_LOCAL_SECRET"))
+	if len(secret) == 0 {
+		if os.Getenv("RUNTIME_PRODUCTION") == "true" {
+			return nil, fmt.Errorf("STORAGE_LOCAL_SECRET must be set in production")
+		}
+		log.Printf("STORAGE_LOCAL_SECRET is not set, upload forms are signed with a random key")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+	return NewLocal(dir, os.Getenv("STORAGE_LOCAL_URL"), secret)
+}
+
+// NewLocal creates the directories of a local storage under dir
+func NewLocal(dir, baseURL string, secret []byte) (*Local, error) {
+	for _, sub := range []string{"files", "meta", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &Local{Dir: dir, BaseURL: strings.TrimSuffix(baseURL, "/"), Secret: secret}, nil
+}
+
+// path maps a key to a file under root. Keys that could escape it are
+// reported as missing objects.
+func (l *Local) path(root, key, suffix string) (string, error) {
+	if key == "" || strings.Contains(key, "\\") || path.Clean("/"+key) != "/"+key {
+		return "", fmt.Errorf("%w: bad key %q", ErrNotFound, key)
+	}
+	return filepath.Join(l.Dir, root, filepath.FromSlash(key)+suffix), nil
+}
+
+func (l *Local) readMeta(key string) (localMeta, error) {
+	var meta localMeta
+	name, err := l.path("meta", key, ".json")
+	if err != nil {
+		return meta, err
+	}
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return meta, nil
+	}
+	if err != nil {
+		return meta, err
+	}
+	return meta, json.Unmarshal(data, &meta)
+}
+
+func (l *Local) writeMeta(key string, meta localMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	name, err := l.path("meta", key, ".json")
+	if err != nil {
+		return err
+	}
+	return l.writeFile(name, bytes.NewReader(data), -1)
+}
+
+// writeFile writes r to name through a temporary file, so readers never see
+// a partial file. With limit >= 0 more than limit bytes fail with
+// ErrUploadSize.
+func (l *Local) writeFile(name string, r io.Reader, limit int64) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Join(l.Dir, "tmp"), "write-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if limit >= 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+	written, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if limit >= 0 && (written == 0 || written > limit) {
+		return ErrUploadSize
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// uploadSignature signs the fields of an upload form
+func (l *Local) uploadSignature(key, contentType, maxSize, expires string) string {
+	mac := hmac.New(sha256.New, l.Secret)
+	mac.Write([]byte(strings.Join([]string{key, contentType, maxSize, expires}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// PresignUpload returns a form for POST /files signed with Secret. The key,
+// content type, size limit and expiry can not be changed by the client.
+func (l *Local) PresignUpload(ctx context.Context, key, contentType string, maxSize int64, expires time.Duration) (string, map[string]string, error) {
+	if _, err := l.path("files", key, ""); err != nil {
+		return "", nil, err
+	}
+	fields := map[string]string{
+		"key":          key,
+		"Content-Type": contentType,
+		"x-max-size":   strconv.FormatInt(maxSize, 10),
+		"x-expires":    strconv.FormatInt(time.Now().Add(expires).Unix(), 10),
+	}
+	fields["x-signature"] = l.uploadSignature(fields["key"], fields["Content-Type"], fields["x-max-size"], fields["x-expires"])
+	return l.BaseURL + "/files", fields, nil
+}
+
+// Receive checks the fields of an upload form and stores the file read from
+// r under the key they were issued for. A form can be posted again until it
+// expires, but not once its object was attached and made permanent.
+func (l *Local) Receive(fields map[string]string, r io.Reader) error {
+	want := l.uploadSignature(fields["key"], fields["Content-Type"], fields["x-max-size"], fields["x-expires"])
+	if !hmac.Equal([]byte(want), []byte(fields["x-signature"])) {
+		return ErrInvalidUpload
+	}
+	expires, err := strconv.ParseInt(fields["x-expires"], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return ErrInvalidUpload
+	}
+	maxSize, err := strconv.ParseInt(fields["x-max-size"], 10, 64)
+	if err != nil {
+		return ErrInvalidUpload
+	}
+
+	key := fields["key"]
+	name, err := l.path("files", key, "")
+	if err != nil {
+		return err
+	}
+	meta, err := l.readMeta(key)
+	if err != nil {
+		return err
+	}
+	if meta.Status == "permanent" {
+		return ErrInvalidUpload
+	}
+	if err := l.writeFile(name, r, maxSize); err != nil {
+		return err
+	}
+	meta.ContentType = fields["Content-Type"]
+	return l.writeMeta(key, meta)
+}
+
+func (l *Local) PublicURL(key string) string {
+	return l.BaseURL + "/files/" + key
+}
+
+func (l *Local) setStatus(key, status string) error {
+	if _, err := l.Stat(context.Background(), key); err != nil {
+		return err
+	}
+	meta, err := l.readMeta(key)
+	if err != nil {
+		return err
+	}
+	meta.Status = status
+	return l.writeMeta(key, meta)
+}
+
+func (l *Local) MarkPermanent(ctx context.Context, key string) error {
+	return l.setStatus(key, "permanent")
+}
+
+func (l *Local) MarkTemporary(ctx context.Context, key string) error {
+	return l.setStatus(key, "temporary")
+}
+
+func (l *Local) IsPermanent(ctx context.Context, key string) (bool, error) {
+	if _, err := l.Stat(ctx, key); err != nil {
+		return false, err
+	}
+	meta, err := l.readMeta(key)
+	return meta.Status == "permanent", err
+}
+
+func (l *Local) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	name, err := l.path("files", key, "")
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	fi, err := os.Stat(name)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && fi.IsDir()) {
+		return ObjectInfo{}, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	meta, err := l.readMeta(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{Key: key, Size: fi.Size(), ContentType: meta.ContentType, LastModified: fi.ModTime()}, nil
+}
+
+// Open returns the file of an object, for serving it with range support
+func (l *Local) Open(key string) (*os.File, ObjectInfo, error) {
+	info, err := l.Stat(context.Background(), key)
+	if err != nil {
+		return nil, info, err
+	}
+	name, _ := l.path("files", key, "")
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, info, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return f, info, err
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error) {
+	return l.Open(key)
+}
+
+func (l *Local) Put(ctx context.Context, key, contentType string, data []byte) error {
+	name, err := l.path("files", key, "")
+	if err != nil {
+		return err
+	}
+	meta, err := l.readMeta(key)
+	if err != nil {
+		return err
+	}
+	if err := l.writeFile(name, bytes.NewReader(data), -1); err != nil {
+		return err
+	}
+	meta.ContentType = contentType
+	return l.writeMeta(key, meta)
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	for _, root := range []string{"files", "meta"} {
+		suffix := ""
+		if root == "meta" {
+			suffix = ".json"
+		}
+		name, err := l.path(root, key, suffix)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		// Empty directories of nested keys go too, removing fails on the
+		// first one that is not empty
+		top := filepath.Join(l.Dir, root)
+		for dir := filepath.Dir(name); dir != top; dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (l *Local) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	root := filepath.Join(l.Dir, "files")
+	return filepath.WalkDir(root, func(name string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // deleted meanwhile
+		}
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := l.Stat(ctx, key)
+		if errors.Is(err, ErrNotFound) {
+			return nil // deleted meanwhile
+		}
+		if err != nil {
+			return err
+		}
+		return fn(info)
+	})
+}
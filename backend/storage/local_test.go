@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLocal(t *testing.T) *Local {
+	local, err := NewLocal(t.TempDir(), "http://backend/", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return local
+}
+
+func TestLocalUploadPolicy(t *testing.T) {
+	local := newTestLocal(t)
+	ctx := context.Background()
+
+	url, fields, err := local.PresignUpload(ctx, "upload", "text/plain", 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "http://backend/files" {
+		t.Errorf("Upload URL: got %q", url)
+	}
+
+	// Every signed field is pinned
+	for _, name := range []string{"key", "Content-Type", "x-max-size", "x-expires"} {
+		tampered := make(map[string]string, len(fields))
+		for k, v := range fields {
+			tampered[k] = v
+		}
+		tampered[name] += "0"
+		if err := local.Receive(tampered, strings.NewReader("data")); !errors.Is(err, ErrInvalidUpload) {
+			t.Errorf("Changed %s: got %v", name, err)
+		}
+	}
+
+	if err := local.Receive(fields, strings.NewReader("")); !errors.Is(err, ErrUploadSize) {
+		t.Errorf("Empty upload: got %v", err)
+	}
+	if err := local.Receive(fields, strings.NewReader("more than ten bytes")); !errors.Is(err, ErrUploadSize) {
+		t.Errorf("Oversized upload: got %v", err)
+	}
+	if _, err := local.Stat(ctx, "upload"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Rejected upload stored: %v", err)
+	}
+
+	if err := local.Receive(fields, strings.NewReader("data")); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	object, info, err := local.Get(ctx, "upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(object)
+	object.Close()
+	if string(data) != "data" || info.ContentType != "text/plain" || info.Size != 4 {
+		t.Errorf("Stored object: got %q %+v", data, info)
+	}
+
+	// Posting the form again replaces a temporary upload, an attached one stays
+	if err := local.Receive(fields, strings.NewReader("again")); err != nil {
+		t.Fatalf("Second upload failed: %v", err)
+	}
+	if err := local.MarkPermanent(ctx, "upload"); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.Receive(fields, strings.NewReader("evil")); !errors.Is(err, ErrInvalidUpload) {
+		t.Errorf("Upload over a permanent object: got %v", err)
+	}
+	if info, _ := local.Stat(ctx, "upload"); info.Size != 5 {
+		t.Errorf("Permanent object replaced: got %+v", info)
+	}
+	if permanent, _ := local.IsPermanent(ctx, "upload"); !permanent {
+		t.Errorf("Permanent object demoted by an upload")
+	}
+
+	_, expired, _ := local.PresignUpload(ctx, "late", "text/plain", 10, -time.Minute)
+	if err := local.Receive(expired, strings.NewReader("data")); !errors.Is(err, ErrInvalidUpload) {
+		t.Errorf("Expired upload: got %v", err)
+	}
+}
+
+func TestLocalKeys(t *testing.T) {
+	local := newTestLocal(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"", "../escape", "a/../../escape", "/absolute", "a//b", "a\\b"} {
+		if err := local.Put(ctx, key, "text/plain", []byte("x")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Key %q: got %v", key, err)
+		}
+	}
+	if got := local.PublicURL("variants/a/320.jpeg"); got != "http://backend/files/variants/a/320.jpeg" {
+		t.Errorf("PublicURL: got %q", got)
+	}
+}
+
+func TestLocalObjects(t *testing.T) {
+	local := newTestLocal(t)
+	ctx := context.Background()
+
+	if _, err := local.IsPermanent(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("IsPermanent of a missing object: got %v", err)
+	}
+	if err := local.MarkPermanent(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("MarkPermanent of a missing object: got %v", err)
+	}
+
+	if err := local.Put(ctx, "photo", "image/png", []byte("png")); err != nil {
+		t.Fatal(err)
+	}
+	if permanent, err := local.IsPermanent(ctx, "photo"); err != nil || permanent {
+		t.Errorf("New object: permanent=%v err=%v", permanent, err)
+	}
+	if err := local.MarkPermanent(ctx, "photo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replacing the content keeps the status
+	if err := local.Put(ctx, "photo", "image/jpeg", []byte("jpeg")); err != nil {
+		t.Fatal(err)
+	}
+	if permanent, err := local.IsPermanent(ctx, "photo"); err != nil || !permanent {
+		t.Errorf("Replaced object: permanent=%v err=%v", permanent, err)
+	}
+	if info, _ := local.Stat(ctx, "photo"); info.ContentType != "image/jpeg" || info.Size != 4 {
+		t.Errorf("Replaced object: got %+v", info)
+	}
+	if err := local.MarkTemporary(ctx, "photo"); err != nil {
+		t.Fatal(err)
+	}
+	if permanent, _ := local.IsPermanent(ctx, "photo"); permanent {
+		t.Errorf("Demoted object is still permanent")
+	}
+
+	for _, width := range []int{320, 800} {
+		if err := local.Put(ctx, VariantKey("photo", width, "jpeg"), "image/jpeg", []byte("small")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var keys []string
+	list := func(prefix string) []string {
+		keys = nil
+		if err := local.List(ctx, prefix, func(info ObjectInfo) error {
+			keys = append(keys, info.Key)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return keys
+	}
+	if got := strings.Join(list(""), ","); got != "photo,variants/photo/320.jpeg,variants/photo/800.jpeg" {
+		t.Errorf("List: got %v", got)
+	}
+	if got := strings.Join(list(VariantsPrefix+"photo/"), ","); got != "variants/photo/320.jpeg,variants/photo/800.jpeg" {
+		t.Errorf("List of variants: got %v", got)
+	}
+
+	// Deleting while listing, as the sweeper does
+	if err := local.List(ctx, "", func(info ObjectInfo) error {
+		return local.Delete(ctx, info.Key)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := list(""); len(got) != 0 {
+		t.Errorf("Deleted objects listed: %v", got)
+	}
+	if err := local.Delete(ctx, "photo"); err != nil {
+		t.Errorf("Deleting a missing object: %v", err)
+	}
+	if _, _, err := local.Get(ctx, "photo"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Deleted object: got %v", err)
+	}
+}
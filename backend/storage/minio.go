@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/tags"
+)
+
+// MinIO keeps objects in an S3 bucket. The status of an object is its
+// "status" tag; the bucket is expected to allow anonymous downloads.
+type MinIO struct {
+	Client *minio.Client
+	Bucket string
+}
+
+// NewMinIOFromEnv connects to MINIO_ENDPOINT and uses MINIO_BUCKET
+func NewMinIOFromEnv() (*MinIO, error) {
+	endpoint := os.Getenv("MINIO_ENDPOINT")
+	accessKey := os.Getenv("MINIO_USERNAME")
+	secretKey := os.Getenv("MINIO_PASSWORD")
+	useSSL := os.Getenv("MINIO_USE_SSL") == "true"
+	if endpoint == "" || accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("MINIO_ENDPOINT, MINIO_USERNAME, and MINIO_PASSWORD must be set")
+	}
+	bucket := os.Getenv("MINIO_BUCKET")
+	if bucket == "" {
+		return nil, fmt.Errorf("MINIO_BUCKET must be set")
+	}
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSSL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MinIO{Client: client, Bucket: bucket}, nil
+}
+
+// PresignUpload creates a POST policy that pins the key and the content type
+// and limits the body to maxSize bytes
+func (m *MinIO) PresignUpload(ctx context.Context, key, contentType string, maxSize int64, expires time.Duration) (string, map[string]string, error) {
+	policy := minio.NewPostPolicy()
+	if err := policy.SetBucket(m.Bucket); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetKey(key); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetExpires(time.Now().UTC().Add(expires)); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetContentType(contentType); err != nil {
+		return "", nil, err
+	}
+	if err := policy.SetContentLengthRange(1, maxSize); err != nil {
+		return "", nil, err
+	}
+
+	postURL, formData, err := m.Client.PresignedPostPolicy(ctx, policy)
+	if err != nil {
+		return "", nil, fmt.Errorf("error generating presigned POST policy: %w", err)
+	}
+
+	// The signature covers the policy only, so the host can be rewritten
+	urlStr, err := replaceHostWithBaseURL(postURL.String())
+	if err != nil {
+		return "", nil, fmt.Errorf("error replacing host with base URL: %w", err)
+	}
+	return urlStr, formData, nil
+}
+
+// PublicURL returns a permanent URL for an object
+func (m *MinIO) PublicURL(key string) string {
+	baseURL := os.Getenv("S3_BASE_URL")
+	if baseURL == "" {
+		// Fallback to a default format if S3_BASE_URL is not set
+		return fmt.Sprintf("/api/files/%s/%s", m.Bucket, key)
+	}
+	return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(baseURL, "/"), m.Bucket, key)
+}
+
+func (m *MinIO) MarkPermanent(ctx context.Context, key string) error {
+	return m.setStatus(ctx, key, "permanent")
+}
+
+func (m *MinIO) MarkTemporary(ctx context.Context, key string) error {
+	return m.setStatus(ctx, key, "temporary")
+}
+
+func (m *MinIO) setStatus(ctx context.Context, key, status string) error {
+	// Other tags of the object are kept
+	t, err := m.Client.GetObjectTagging(ctx, m.Bucket, key, minio.GetObjectTaggingOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting object tags: %w", wrapNotFound(err))
+	}
+	tagsMap := t.ToMap()
+	if tagsMap == nil {
+		tagsMap = make(map[string]string)
+	}
+	tagsMap["status"] = status
+
+	newTags, err := tags.NewTags(tagsMap, false)
+	if err != nil {
+		return fmt.Errorf("error creating new tags: %w", err)
+	}
+	if err := m.Client.PutObjectTagging(ctx, m.Bucket, key, newTags, minio.PutObjectTaggingOptions{}); err != nil {
+		return fmt.Errorf("error setting object tags: %w", wrapNotFound(err))
+	}
+	return nil
+}
+
+func (m *MinIO) IsPermanent(ctx context.Context, key string) (bool, error) {
+	t, err := m.Client.GetObjectTagging(ctx, m.Bucket, key, minio.GetObjectTaggingOptions{})
+	if err != nil {
+		return false, fmt.Errorf("error getting object tags: %w", wrapNotFound(err))
+	}
+	return t.ToMap()["status"] == "permanent", nil
+}
+
+func (m *MinIO) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	info, err := m.Client.StatObject(ctx, m.Bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return ObjectInfo{}, wrapNotFound(err)
+	}
+	return objectInfo(info), nil
+}
+
+func (m *MinIO) Get(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error) {
+	object, err := m.Client.GetObject(ctx, m.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, ObjectInfo{}, wrapNotFound(err)
+	}
+	// GetObject is lazy, Stat is the first request that reaches the server
+	info, err := object.Stat()
+	if err != nil {
+		object.Close()
+		return nil, ObjectInfo{}, wrapNotFound(err)
+	}
+	return object, objectInfo(info), nil
+}
+
+func (m *MinIO) Put(ctx context.Context, key, contentType string, data []byte) error {
+	opts := minio.PutObjectOptions{ContentType: contentType}
+	t, err := m.Client.GetObjectTagging(ctx, m.Bucket, key, minio.GetObjectTaggingOptions{})
+	if err == nil {
+		opts.UserTags = t.ToMap()
+	} else if !isNotFound(err) {
+		return fmt.Errorf("error getting object tags: %w", err)
+	}
+
+	if _, err := m.Client.PutObject(ctx, m.Bucket, key, bytes.NewReader(data), int64(len(data)), opts); err != nil {
+		return fmt.Errorf("error uploading object: %w", err)
+	}
+	return nil
+}
+
+func (m *MinIO) Delete(ctx context.Context, key string) error {
+	if err := m.Client.RemoveObject(ctx, m.Bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("error removing object: %w", err)
+	}
+	return nil
+}
+
+func (m *MinIO) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	for object := range m.Client.ListObjects(ctx, m.Bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return object.Err
+		}
+		if err := fn(objectInfo(object)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func objectInfo(info minio.ObjectInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:          info.Key,
+		Size:         info.Size,
+		ContentType:  info.ContentType,
+		LastModified: info.LastModified,
+	}
+}
+
+// isNotFound reports whether a MinIO error means the object does not exist
+func isNotFound(err error) bool {
+	var resp minio.ErrorResponse
+	if errors.As(err, &resp) {
+		return resp.Code == "NoSuchKey" || resp.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// wrapNotFound turns "no such key" responses into ErrNotFound
+func wrapNotFound(err error) error {
+	if isNotFound(err) {
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return err
+}
+
+// replaceHostWithBaseURL replaces the host part of a URL with the S3_BASE_URL if set
+func replaceHostWithBaseURL(originalURL string) (string, error) {
+	baseURL := os.Getenv("S3_BASE_URL")
+	if baseURL == "" {
+		return originalURL, nil // Return original URL if S3_BASE_URL is not set
+	}
+
+	u, err := url.Parse(originalURL)
+	if err != nil {
+		return "", fmt.Errorf("error parsing URL: %w", err)
+	}
+
+	baseU, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("error parsing base URL: %w", err)
+	}
+
+	// Replace the scheme, host, and port with those from the base URL
+	u.Scheme = baseU.Scheme
+	u.Host = baseU.Host
+
+	// Preserve the path from the base URL if it exists and append the original path
+	if baseU.Path != "" && baseU.Path != "/" {
+		// Ensure base path doesn't end with slash and original path doesn't start with slash
+		basePath := strings.TrimSuffix(baseU.Path, "/")
+		origPath := strings.TrimPrefix(u.Path, "/")
+		u.Path = basePath + "/" + origPath
+	}
+
+	return u.String(), nil
+}
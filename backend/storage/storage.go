@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+// Storage keeps media objects. Fresh uploads are temporary until they are
+// marked permanent; the sweeper deletes temporary objects after a while.
+type Storage interface {
+	// PresignUpload returns the URL and form fields of a browser POST upload
+	// of exactly one object with the given type and at most maxSize bytes
+	PresignUpload(ctx context.Context, key, contentType string, maxSize int64, expires time.Duration) (string, map[string]string, error)
+	MarkPermanent(ctx context.Context, key string) error
+	MarkTemporary(ctx context.Context, key string) error
+	IsPermanent(ctx context.Context, key string) (bool, error)
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error)
+	// Put stores data under key. An existing object is replaced and keeps
+	// its status; new objects carry none.
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	// Delete removes an object. Missing objects are not an error.
+	Delete(ctx context.Context, key string) error
+	// List calls fn for every object whose key starts with prefix
+	List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error
+	PublicURL(key string) string
+}
+
+// VariantsPrefix is the key prefix of resized copies of media. Their
+// lifetime follows the original object, so they carry no status.
+const VariantsPrefix = "variants/"
+
+// VariantKey returns the key of the copy of objectKey resized to width
+func VariantKey(objectKey string, width int, format string) string {
+	return fmt.Sprintf("%s%s/%d.%s", VariantsPrefix, objectKey, width, format)
+}
+
+// NewFromEnv configures the backend chosen by STORAGE_BACKEND: "minio" (the
+// default) or "local"
+func NewFromEnv() (Storage, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "minio":
+		return NewMinIOFromEnv()
+	case "local":
+		return NewLocalFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", backend)
+	}
+}
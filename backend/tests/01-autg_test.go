@@ -133,4 +133,40 @@ func TestRefreshToken(t *testing.T) {
     if resp.StatusCode != 401 {
         t.Errorf("Refresh: expected 401, got %d", resp.StatusCode)
     }
-}
\ No newline at end of file
+}
+func TestLegacyPasswordRehash(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    if format := passwordFormat(t, username); format != "phc" {
+        t.Fatalf("New account: expected phc hash, got %s", format)
+    }
+
+    // Хеш старого формата с общей солью PASSWORD_SALT
+    b, _ := json.Marshal(map[string]string{"password": password})
+    req, _ := http.NewRequest("PUT", apiBase+"/dev/users/"+username+"/legacy-password", bytes.NewReader(b))
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil || resp.StatusCode != 200 {
+        t.Fatalf("Setting legacy password failed: %v", err)
+    }
+    resp.Body.Close()
+    if format := passwordFormat(t, username); format != "legacy" {
+        t.Fatalf("Expected legacy hash, got %s", format)
+    }
+
+    // Вход со старым хешем работает и переводит его в PHC
+    LoginUser(t, username, password)
+    if format := passwordFormat(t, username); format != "phc" {
+        t.Errorf("Legacy hash was not rehashed on login, got %s", format)
+    }
+    LoginUser(t, username, password)
+}
+
+func passwordFormat(t *testing.T, username string) string {
+    var out struct {
+        Format string `json:"format"`
+    }
+    getJSON(t, apiBase+"/dev/users/"+username+"/password-format", 200, &out)
+    return out.Format
+}
@@ -17,6 +17,7 @@ type Article struct {
     Media   []struct {
         FileName string `json:"file_name"`
         S3Key    string `json:"s3_key"`
+        Variants map[string]string `json:"variants"`
     } `json:"media"`
 }
 
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strings"
@@ -17,24 +18,38 @@ type MediaFile struct {
 	S3Key    string `json:"s3_key"`
 }
 
-// Helper function to upload temporary media file and get its URL
-func uploadTempMedia(t *testing.T, accessToken string) string {
-	req, _ := http.NewRequest("POST", apiBase+"/media/upload-temp", nil)
+// TempUpload - выданная бэкендом загрузка: POST формы на URL
+type TempUpload struct {
+	URL      string            `json:"temp_url"`
+	FormData map[string]string `json:"form_data"`
+	FileID   string            `json:"file_id"`
+}
+
+// Helper function to request a temporary upload of a small text file
+func uploadTempMedia(t *testing.T, accessToken string) TempUpload {
+	status, upload := requestUpload(t, accessToken, map[string]interface{}{
+		"file_name": "file.txt", "content_type": "text/plain", "size": 64,
+	})
+	if status != 200 {
+		t.Fatalf("Upload-temp request failed with status: %d", status)
+	}
+	return upload
+}
+
+func requestUpload(t *testing.T, accessToken string, body interface{}) (int, TempUpload) {
+	b, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", apiBase+"/media/upload-temp", bytes.NewReader(b))
 	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		t.Fatalf("Failed to get temp upload URL: %v", err)
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		t.Fatalf("Upload-temp request failed with status: %d", resp.StatusCode)
-	}
-	
-	var out struct {
-		URL string `json:"temp_url"`
-	}
+
+	var out TempUpload
 	json.NewDecoder(resp.Body).Decode(&out)
-	return out.URL
+	return resp.StatusCode, out
 }
 
 // Helper to extract file key from presigned URL
@@ -55,12 +70,12 @@ func TestUploadSingleMediaAndCreateArticle(t *testing.T) {
     access, _ := LoginUser(t, username, password)
 
     // Получаем временный URL для загрузки
-    uploadURL := uploadTempMedia(t, access)
+    upload := uploadTempMedia(t, access)
     
     // Загружаем файл по presigned URL
     fileContent := []byte("hello world media")
-    putResp, err := httpPut(uploadURL, fileContent)
-    log.Printf("Upload URL: %s", uploadURL)
+    putResp, err := postUpload(upload, fileContent)
+    log.Printf("Upload URL: %s", upload.URL)
     if err != nil {
         t.Fatalf("Failed to upload media: %v", err)
     }
@@ -69,7 +84,7 @@ func TestUploadSingleMediaAndCreateArticle(t *testing.T) {
     }
 
     // Создаем статью, ссылаясь на загруженный файл
-    fileKey := extractFileKeyFromURL(uploadURL)
+    fileKey := upload.FileID
     
     title := "Article with media"
     content := "Some content"
@@ -130,19 +145,19 @@ func TestUploadMultipleMediaAndCreateArticle(t *testing.T) {
     access, _ := LoginUser(t, username, password)
 
     // Загружаем несколько файлов
-    uploadURLs := []string{}
+    uploads := []TempUpload{}
     fileKeys := []string{}
     for i := 0; i < 2; i++ {
-        uploadURL := uploadTempMedia(t, access)
-        putResp, err := httpPut(uploadURL, []byte("media content"))
+        upload := uploadTempMedia(t, access)
+        putResp, err := postUpload(upload, []byte("media content"))
         if err != nil {
             t.Fatalf("Failed to upload media: %v", err)
         }
         if putResp.StatusCode != 200 && putResp.StatusCode != 204 {
             t.Fatalf("Media upload failed, status: %d", putResp.StatusCode)
         }
-        uploadURLs = append(uploadURLs, uploadURL)
-        fileKeys = append(fileKeys, extractFileKeyFromURL(uploadURL))
+        uploads = append(uploads, upload)
+        fileKeys = append(fileKeys, upload.FileID)
     }
 
     // Создаем статью с несколькими медиафайлами
@@ -165,13 +180,13 @@ func TestUploadMultipleMediaAndCreateArticle(t *testing.T) {
         Media []MediaFile `json:"media"`
     }
     json.NewDecoder(resp.Body).Decode(&out)
-    if len(out.Media) != len(uploadURLs) {
-        t.Fatalf("Expected %d media, got %d", len(uploadURLs), len(out.Media))
+    if len(out.Media) != len(uploads) {
+        t.Fatalf("Expected %d media, got %d", len(uploads), len(out.Media))
     }
     
     got := GetArticle(t, out.ID, 200)
-    if len(got.Media) != len(uploadURLs) {
-        t.Errorf("GetArticle: expected %d media, got %d", len(uploadURLs), len(got.Media))
+    if len(got.Media) != len(uploads) {
+        t.Errorf("GetArticle: expected %d media, got %d", len(uploads), len(got.Media))
     }
     
     // Добавляем проверку содержимого всех файлов
@@ -199,9 +214,9 @@ func TestUpdateArticleMedia(t *testing.T) {
     access, _ := LoginUser(t, username, password)
 
     // Загружаем первый файл
-    oldMediaURL := uploadTempMedia(t, access)
-    httpPut(oldMediaURL, []byte("old media"))
-    oldFileKey := extractFileKeyFromURL(oldMediaURL)
+    oldUpload := uploadTempMedia(t, access)
+    postUpload(oldUpload, []byte("old media"))
+    oldFileKey := oldUpload.FileID
 
     // Создаем статью с одним медиафайлом
     body := map[string]interface{}{"title": "UpdateMedia", "content": "Update", "media": []string{oldFileKey}}
@@ -222,9 +237,9 @@ func TestUpdateArticleMedia(t *testing.T) {
     json.NewDecoder(resp.Body).Decode(&out)
 
     // Загружаем новый файл
-    newMediaURL := uploadTempMedia(t, access)
-    httpPut(newMediaURL, []byte("new media"))
-    newFileKey := extractFileKeyFromURL(newMediaURL)
+    newUpload := uploadTempMedia(t, access)
+    postUpload(newUpload, []byte("new media"))
+    newFileKey := newUpload.FileID
 
     // Обновляем статью с новым медиа
     // При обновлении сохраняем те же title и content
@@ -283,7 +298,7 @@ func TestExpiredPresignedURL(t *testing.T) {
     access, _ := LoginUser(t, username, password)
 
     // Получаем временный URL для загрузки
-    uploadURL := uploadTempMedia(t, access)
+    upload := uploadTempMedia(t, access)
 
     // Ждем истечения времени
     time.Sleep(10 * time.Second) 
@@ -293,7 +308,7 @@ func TestExpiredPresignedURL(t *testing.T) {
     // Если URL не истекает, тест будет помечен как пропущенный
     
     // Пытаемся загрузить файл
-    putResp, err := httpPut(uploadURL, []byte("expired"))
+    putResp, err := postUpload(upload, []byte("expired"))
     if err == nil && (putResp.StatusCode == 200 || putResp.StatusCode == 204) {
         t.Skip("URL did not expire as expected. This test requires special backend configuration.")
     }
@@ -329,13 +344,25 @@ func TestGetArticleWithoutMedia(t *testing.T) {
     }
 }
 
-// Вспомогательная функция для PUT-запроса
-func httpPut(url string, data []byte) (*http.Response, error) {
-    req, err := http.NewRequest("PUT", url, bytes.NewReader(data))
+// postUpload отправляет файл формой по выданной загрузке, как это делает браузер
+func postUpload(upload TempUpload, data []byte) (*http.Response, error) {
+    var body bytes.Buffer
+    form := multipart.NewWriter(&body)
+    for name, value := range upload.FormData {
+        form.WriteField(name, value)
+    }
+    file, err := form.CreateFormFile("file", "file")
     if err != nil {
         return nil, err
     }
-    req.Header.Set("Content-Type", "application/octet-stream")
+    file.Write(data)
+    form.Close()
+
+    req, err := http.NewRequest("POST", upload.URL, &body)
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", form.FormDataContentType())
     client := &http.Client{}
     return client.Do(req)
-}
\ No newline at end of file
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+type ArticleList struct {
+	Articles   []Article `json:"articles"`
+	NextCursor string    `json:"next_cursor"`
+}
+
+func TestArticleListPagination(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    for i := 0; i < 5; i++ {
+        CreateArticle(t, access, fmt.Sprintf("Article %d", i), "content", 201)
+    }
+
+    // Первая страница
+    page := ListArticles(t, url.Values{"limit": {"2"}}, 200)
+    if len(page.Articles) != 2 || page.NextCursor == "" {
+        t.Fatalf("First page: expected 2 articles and cursor, got %d, %q", len(page.Articles), page.NextCursor)
+    }
+    if page.Articles[0].Title != "Article 4" {
+        t.Errorf("Default order: expected newest first, got %s", page.Articles[0].Title)
+    }
+
+    // Обходим все страницы
+    seen := map[string]bool{}
+    for _, a := range page.Articles {
+        seen[a.UUID] = true
+    }
+    for page.NextCursor != "" {
+        page = ListArticles(t, url.Values{"limit": {"2"}, "cursor": {page.NextCursor}}, 200)
+        for _, a := range page.Articles {
+            if seen[a.UUID] {
+                t.Errorf("Article %s returned twice", a.UUID)
+            }
+            seen[a.UUID] = true
+        }
+    }
+    if len(seen) != 5 {
+        t.Errorf("Pagination: expected 5 articles, got %d", len(seen))
+    }
+
+    // Сортировка по заголовку
+    page = ListArticles(t, url.Values{"sort": {"title"}, "order": {"asc"}}, 200)
+    if len(page.Articles) != 5 || page.Articles[0].Title != "Article 0" {
+        t.Errorf("Sort by title: unexpected order")
+    }
+
+    // Невалидные параметры
+    ListArticles(t, url.Values{"sort": {"author"}}, 400)
+    ListArticles(t, url.Values{"limit": {"1000"}}, 400)
+    ListArticles(t, url.Values{"cursor": {"garbage"}}, 400)
+    // Курсор с не-UUID id и курсор другого порядка сортировки
+    badID := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"created","o":"desc","v":"2024-01-01T00:00:00Z","id":"x"}`))
+    ListArticles(t, url.Values{"cursor": {badID}}, 400)
+    page = ListArticles(t, url.Values{"limit": {"2"}}, 200)
+    ListArticles(t, url.Values{"limit": {"2"}, "order": {"asc"}, "cursor": {page.NextCursor}}, 400)
+    ListArticles(t, url.Values{"from": {"yesterday"}}, 400)
+}
+
+func TestArticleListFilters(t *testing.T) {
+    ResetDB(t)
+    first, password := UniqueNamedUser("first")
+    RegisterUser(t, first, password)
+    firstAccess, _ := LoginUser(t, first, password)
+    second, password := UniqueNamedUser("second")
+    RegisterUser(t, second, password)
+    secondAccess, _ := LoginUser(t, second, password)
+
+    CreateArticle(t, firstAccess, "First rule", "content", 201)
+    CreateArticle(t, secondAccess, "Second rule", "content", 201)
+    CreateArticle(t, secondAccess, "Third rule", "content", 201)
+
+    page := ListArticles(t, url.Values{"author": {second}}, 200)
+    if len(page.Articles) != 2 {
+        t.Fatalf("Author filter: expected 2 articles, got %d", len(page.Articles))
+    }
+    for _, a := range page.Articles {
+        if a.Author != second {
+            t.Errorf("Author filter: got article by %s", a.Author)
+        }
+    }
+
+    page = ListArticles(t, url.Values{"from": {"2000-01-01T00:00:00Z"}, "to": {"2001-01-01T00:00:00Z"}}, 200)
+    if len(page.Articles) != 0 {
+        t.Errorf("Date filter: expected no articles, got %d", len(page.Articles))
+    }
+}
+
+func ListArticles(t *testing.T, params url.Values, wantStatus int) ArticleList {
+    resp, err := http.Get(apiBase + "/articles?" + params.Encode())
+    if err != nil {
+        t.Fatalf("ListArticles failed: %v", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != wantStatus {
+        t.Fatalf("ListArticles: expected %d, got %d", wantStatus, resp.StatusCode)
+    }
+    var out ArticleList
+    if wantStatus == 200 {
+        json.NewDecoder(resp.Body).Decode(&out)
+    }
+    return out
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type SearchResult struct {
+	ID             string  `json:"id"`
+	Title          string  `json:"title"`
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"`
+	Rank           float64 `json:"rank"`
+}
+
+func TestArticleSearch(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    titleHit := CreateArticle(t, access, "Parking rules", "Where to leave bicycles", 201)
+    contentHit := CreateArticle(t, access, "Office guide", "Parking is available behind the building", 201)
+    CreateArticle(t, access, "Kitchen", "Wash your own cups", 201)
+
+    // Заголовок весит больше, чем содержимое
+    results := SearchArticles(t, "parking", 200)
+    if len(results) != 2 {
+        t.Fatalf("Search: expected 2 results, got %d", len(results))
+    }
+    if results[0].ID != titleHit || results[1].ID != contentHit {
+        t.Errorf("Search: title match should rank first")
+    }
+    if !strings.Contains(results[1].Snippet, "<mark>Parking</mark>") {
+        t.Errorf("Search: snippet not highlighted: %s", results[1].Snippet)
+    }
+
+    // Фраза
+    results = SearchArticles(t, `"behind the building"`, 200)
+    if len(results) != 1 || results[0].ID != contentHit {
+        t.Errorf("Phrase search: expected only the office guide")
+    }
+    results = SearchArticles(t, `"building the behind"`, 200)
+    if len(results) != 0 {
+        t.Errorf("Phrase search: word order must matter, got %d results", len(results))
+    }
+
+    // Префикс
+    results = SearchArticles(t, "bicyc*", 200)
+    if len(results) != 1 || results[0].ID != titleHit {
+        t.Errorf("Prefix search: expected only the parking rules")
+    }
+
+    // Пустой запрос
+    SearchArticles(t, "", 400)
+    SearchArticles(t, `"" *`, 400)
+}
+
+func SearchArticles(t *testing.T, q string, wantStatus int) []SearchResult {
+    resp, err := http.Get(apiBase + "/articles/search?" + url.Values{"q": {q}}.Encode())
+    if err != nil {
+        t.Fatalf("SearchArticles failed: %v", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != wantStatus {
+        t.Fatalf("SearchArticles: expected %d, got %d", wantStatus, resp.StatusCode)
+    }
+    var out struct {
+        Results []SearchResult `json:"results"`
+    }
+    if wantStatus == 200 {
+        json.NewDecoder(resp.Body).Decode(&out)
+    }
+    return out.Results
+}
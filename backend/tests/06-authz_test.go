@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestArticleOwnership(t *testing.T) {
+    ResetDB(t)
+    // Первый пользователь становится администратором
+    admin, password := UniqueNamedUser("admin")
+    RegisterUser(t, admin, password)
+    adminAccess, _ := LoginUser(t, admin, password)
+
+    author, password := UniqueNamedUser("author")
+    RegisterUser(t, author, password)
+    authorAccess, _ := LoginUser(t, author, password)
+
+    other, password := UniqueNamedUser("other")
+    RegisterUser(t, other, password)
+    otherAccess, _ := LoginUser(t, other, password)
+
+    articleUUID := CreateArticle(t, authorAccess, "Owned rule", "content", 201)
+
+    // Чужую статью менять нельзя
+    UpdateArticleForbidden(t, otherAccess, articleUUID)
+
+    // Автор и администратор могут
+    UpdateArticle(t, authorAccess, articleUUID, "Owned rule", "by author", 200)
+    UpdateArticle(t, adminAccess, articleUUID, "Owned rule", "by admin", 200)
+
+    // Редактор может менять любые статьи
+    SetUserRole(t, adminAccess, other, "editor", 200)
+    otherAccess, _ = LoginUser(t, other, password)
+    UpdateArticle(t, otherAccess, articleUUID, "Owned rule", "by editor", 200)
+
+    // Читатель не может создавать статьи
+    SetUserRole(t, adminAccess, author, "reader", 200)
+    authorAccess, _ = LoginUser(t, author, password)
+    CreateArticle(t, authorAccess, "Reader rule", "content", 403)
+    UpdateArticleForbidden(t, authorAccess, articleUUID)
+}
+
+func TestAdminRoleManagement(t *testing.T) {
+    ResetDB(t)
+    admin, password := UniqueNamedUser("admin")
+    RegisterUser(t, admin, password)
+    adminAccess, _ := LoginUser(t, admin, password)
+
+    user, password := UniqueNamedUser("user")
+    RegisterUser(t, user, password)
+    userAccess, _ := LoginUser(t, user, password)
+
+    // Только администратор управляет ролями
+    SetUserRole(t, userAccess, user, "admin", 403)
+    SetUserRole(t, adminAccess, user, "superuser", 400)
+    SetUserRole(t, adminAccess, "nobody_here", "editor", 404)
+
+    // Последнего администратора понизить нельзя
+    SetUserRole(t, adminAccess, admin, "author", 409)
+    SetUserRole(t, adminAccess, user, "admin", 200)
+    SetUserRole(t, adminAccess, admin, "author", 200)
+}
+
+func TestFirstAdminIsUnique(t *testing.T) {
+    ResetDB(t)
+
+    // Одновременные регистрации в пустой базе дают ровно одного администратора
+    var users []string
+    var wg sync.WaitGroup
+    for i := 0; i < 8; i++ {
+        username, password := UniqueNamedUser(fmt.Sprintf("first%d", i))
+        users = append(users, username)
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            b, _ := json.Marshal(map[string]string{"username": username, "password": password})
+            resp, err := http.Post(apiBase+"/auth/register", "application/json", bytes.NewReader(b))
+            if err == nil {
+                resp.Body.Close()
+            }
+        }()
+    }
+    wg.Wait()
+
+    admins := 0
+    for _, username := range users {
+        var page struct {
+            Profile struct {
+                Role string `json:"role"`
+            } `json:"profile"`
+        }
+        getJSON(t, apiBase+"/users/"+username, 200, &page)
+        if page.Profile.Role == "admin" {
+            admins++
+        }
+    }
+    if admins != 1 {
+        t.Errorf("Expected exactly one admin, got %d", admins)
+    }
+}
+
+func UpdateArticleForbidden(t *testing.T, access, uuid string) {
+    body := map[string]string{"content": "hijacked"}
+    b, _ := json.Marshal(body)
+    req, _ := http.NewRequest("PUT", fmt.Sprintf("%s/articles/%s", apiBase, uuid), bytes.NewReader(b))
+    req.Header.Set("Authorization", "Bearer "+access)
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("UpdateArticle failed: %v", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != 403 {
+        t.Fatalf("UpdateArticle: expected 403, got %d", resp.StatusCode)
+    }
+    var out map[string]string
+    json.NewDecoder(resp.Body).Decode(&out)
+    if out["message"] != "Forbidden" {
+        t.Errorf("UpdateArticle: unexpected error body %v", out)
+    }
+}
+
+func SetUserRole(t *testing.T, access, username, role string, wantStatus int) {
+    b, _ := json.Marshal(map[string]string{"role": role})
+    req, _ := http.NewRequest("PUT", fmt.Sprintf("%s/admin/users/%s/role", apiBase, username), bytes.NewReader(b))
+    req.Header.Set("Authorization", "Bearer "+access)
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("SetUserRole failed: %v", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != wantStatus {
+        t.Fatalf("SetUserRole: expected %d, got %d", wantStatus, resp.StatusCode)
+    }
+}
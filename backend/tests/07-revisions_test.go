@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type Revision struct {
+	Number       int    `json:"number"`
+	Title        string `json:"title"`
+	Content      string `json:"content"`
+	Author       string `json:"author"`
+	RestoredFrom *int   `json:"restored_from"`
+}
+
+func TestArticleRevisions(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    articleUUID := CreateArticle(t, access, "Revisioned", "line one\nline two\nline three", 201)
+    UpdateArticle(t, access, articleUUID, "Revisioned", "line one\nline 2\nline three", 200)
+    // Без изменений текста новая ревизия не создается
+    UpdateArticle(t, access, articleUUID, "Revisioned", "line one\nline 2\nline three", 200)
+
+    revisions := ListRevisions(t, articleUUID)
+    if len(revisions) != 2 {
+        t.Fatalf("Expected 2 revisions, got %d", len(revisions))
+    }
+    if revisions[0].Number != 2 || revisions[0].Author != username {
+        t.Errorf("Unexpected newest revision: %+v", revisions[0])
+    }
+
+    first := GetRevision(t, articleUUID, 1, 200)
+    if first.Content != "line one\nline two\nline three" {
+        t.Errorf("Revision 1 content mismatch: %q", first.Content)
+    }
+    GetRevision(t, articleUUID, 42, 404)
+
+    // Дифф между ревизиями
+    var diff struct {
+        Diff string `json:"diff"`
+    }
+    getJSON(t, fmt.Sprintf("%s/articles/%s/revisions/diff?from=1&to=2", apiBase, articleUUID), 200, &diff)
+    if !strings.Contains(diff.Diff, "\n-line two\n+line 2\n") {
+        t.Errorf("Unexpected diff:\n%s", diff.Diff)
+    }
+    getJSON(t, fmt.Sprintf("%s/articles/%s/revisions/diff?from=1", apiBase, articleUUID), 400, nil)
+
+    // Восстановление старой ревизии создает новую
+    req, _ := http.NewRequest("POST", fmt.Sprintf("%s/articles/%s/revisions/1/restore", apiBase, articleUUID), nil)
+    req.Header.Set("Authorization", "Bearer "+access)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("Restore failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 200 {
+        t.Fatalf("Restore: expected 200, got %d", resp.StatusCode)
+    }
+
+    got := GetArticle(t, articleUUID, 200)
+    if got.Content != first.Content {
+        t.Errorf("Restore: article content not restored")
+    }
+    head := GetRevision(t, articleUUID, 3, 200)
+    if head.RestoredFrom == nil || *head.RestoredFrom != 1 {
+        t.Errorf("Restore: revision 3 should be restored from 1")
+    }
+
+    // Ревизии статьи в корзине не отдаются
+    SendArticleAction(t, access, "DELETE", articleUUID, "", 200)
+    GetRevision(t, articleUUID, 1, 404)
+    getJSON(t, fmt.Sprintf("%s/articles/%s/revisions/diff?from=1&to=2", apiBase, articleUUID), 404, nil)
+}
+
+func TestConcurrentRevisions(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+    articleUUID := CreateArticle(t, access, "Busy", "initial", 201)
+
+    // Одновременные правки получают разные номера ревизий, а не 500
+    statuses := make(chan int, 8)
+    for i := 0; i < 8; i++ {
+        go func(i int) {
+            b, _ := json.Marshal(map[string]string{"content": fmt.Sprintf("edit %d", i)})
+            req, _ := http.NewRequest("PUT", fmt.Sprintf("%s/articles/%s", apiBase, articleUUID), bytes.NewReader(b))
+            req.Header.Set("Authorization", "Bearer "+access)
+            req.Header.Set("Content-Type", "application/json")
+            resp, err := http.DefaultClient.Do(req)
+            if err != nil {
+                statuses <- 0
+                return
+            }
+            resp.Body.Close()
+            statuses <- resp.StatusCode
+        }(i)
+    }
+    for i := 0; i < 8; i++ {
+        if status := <-statuses; status != 200 {
+            t.Errorf("Concurrent update: expected 200, got %d", status)
+        }
+    }
+    if revisions := ListRevisions(t, articleUUID); len(revisions) != 9 {
+        t.Errorf("Expected 9 revisions, got %d", len(revisions))
+    }
+}
+
+func ListRevisions(t *testing.T, articleUUID string) []Revision {
+    var out struct {
+        Revisions []Revision `json:"revisions"`
+    }
+    getJSON(t, fmt.Sprintf("%s/articles/%s/revisions", apiBase, articleUUID), 200, &out)
+    return out.Revisions
+}
+
+func GetRevision(t *testing.T, articleUUID string, number int, wantStatus int) Revision {
+    var out Revision
+    getJSON(t, fmt.Sprintf("%s/articles/%s/revisions/%d", apiBase, articleUUID, number), wantStatus, &out)
+    return out
+}
+
+// getJSON выполняет GET-запрос и декодирует ответ в out при статусе 200
+func getJSON(t *testing.T, url string, wantStatus int, out interface{}) {
+    resp, err := http.Get(url)
+    if err != nil {
+        t.Fatalf("GET %s failed: %v", url, err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != wantStatus {
+        t.Fatalf("GET %s: expected %d, got %d", url, wantStatus, resp.StatusCode)
+    }
+    if out != nil && wantStatus == 200 {
+        json.NewDecoder(resp.Body).Decode(out)
+    }
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestArticleTrash(t *testing.T) {
+    ResetDB(t)
+    owner, password := UniqueNamedUser("owner")
+    RegisterUser(t, owner, password)
+    ownerAccess, _ := LoginUser(t, owner, password)
+    other, password := UniqueNamedUser("other")
+    RegisterUser(t, other, password)
+    otherAccess, _ := LoginUser(t, other, password)
+
+    articleUUID := CreateArticle(t, otherAccess, "Trash me", "content", 201)
+
+    // Удалять может только владелец (или редактор)
+    SendArticleAction(t, ownerAccess, "DELETE", articleUUID, "", 200) // owner - первый пользователь, admin
+    GetArticle(t, articleUUID, 404)
+
+    trash := ListTrash(t, otherAccess)
+    if len(trash) != 1 || trash[0].UUID != articleUUID {
+        t.Fatalf("Trash: expected the deleted article, got %d items", len(trash))
+    }
+    page := ListArticles(t, nil, 200)
+    if len(page.Articles) != 0 {
+        t.Errorf("List: deleted article must be hidden")
+    }
+
+    // Восстановление
+    SendArticleAction(t, otherAccess, "POST", articleUUID, "/restore", 200)
+    GetArticle(t, articleUUID, 200)
+    SendArticleAction(t, otherAccess, "POST", articleUUID, "/restore", 404)
+
+    // Окончательное удаление только из корзины
+    SendArticleAction(t, otherAccess, "DELETE", articleUUID, "/purge", 404)
+    SendArticleAction(t, otherAccess, "DELETE", articleUUID, "", 200)
+    SendArticleAction(t, otherAccess, "DELETE", articleUUID, "/purge", 200)
+    if len(ListTrash(t, otherAccess)) != 0 {
+        t.Errorf("Trash: purged article still listed")
+    }
+    SendArticleAction(t, otherAccess, "POST", articleUUID, "/restore", 404)
+}
+
+func TestArticleDeleteForbidden(t *testing.T) {
+    ResetDB(t)
+    admin, password := UniqueNamedUser("admin")
+    RegisterUser(t, admin, password)
+    owner, password := UniqueNamedUser("owner")
+    RegisterUser(t, owner, password)
+    ownerAccess, _ := LoginUser(t, owner, password)
+    other, password := UniqueNamedUser("other")
+    RegisterUser(t, other, password)
+    otherAccess, _ := LoginUser(t, other, password)
+
+    articleUUID := CreateArticle(t, ownerAccess, "Not yours", "content", 201)
+    SendArticleAction(t, otherAccess, "DELETE", articleUUID, "", 403)
+    SendArticleAction(t, ownerAccess, "DELETE", articleUUID, "", 200)
+    SendArticleAction(t, otherAccess, "POST", articleUUID, "/restore", 403)
+    SendArticleAction(t, otherAccess, "DELETE", articleUUID, "/purge", 403)
+}
+
+func TestPurgeRemovesMedia(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    upload := uploadTempMedia(t, access)
+    postUpload(upload, []byte("purge me"))
+    fileKey := upload.FileID
+    articleUUID := CreateArticleWithMedia(t, access, "With media", "content", []string{fileKey}, 201)
+    mediaURL := GetArticle(t, articleUUID, 200).Media[0].S3Key
+
+    SendArticleAction(t, access, "DELETE", articleUUID, "", 200)
+    SendArticleAction(t, access, "DELETE", articleUUID, "/purge", 200)
+
+    resp, err := http.Get(mediaURL)
+    if err != nil {
+        t.Fatalf("Failed to get media file: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 404 {
+        t.Errorf("Purge: media object still available, status %d", resp.StatusCode)
+    }
+}
+
+// SendArticleAction выполняет запрос без тела к /articles/{uuid}{suffix}
+func SendArticleAction(t *testing.T, access, method, uuid, suffix string, wantStatus int) {
+    req, _ := http.NewRequest(method, fmt.Sprintf("%s/articles/%s%s", apiBase, uuid, suffix), nil)
+    req.Header.Set("Authorization", "Bearer "+access)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("%s %s failed: %v", method, suffix, err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != wantStatus {
+        t.Fatalf("%s /articles/%s%s: expected %d, got %d", method, uuid, suffix, wantStatus, resp.StatusCode)
+    }
+}
+
+func ListTrash(t *testing.T, access string) []Article {
+    req, _ := http.NewRequest("GET", apiBase+"/articles/trash", nil)
+    req.Header.Set("Authorization", "Bearer "+access)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("ListTrash failed: %v", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != 200 {
+        t.Fatalf("ListTrash: expected 200, got %d", resp.StatusCode)
+    }
+    var out ArticleList
+    json.NewDecoder(resp.Body).Decode(&out)
+    return out.Articles
+}
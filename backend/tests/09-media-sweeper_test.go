@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSweeperRemovesAbandonedUploads(t *testing.T) {
+    // Требует MEDIA_TEMP_TTL=10 на бэкенде (см. test.docker-compose.yml)
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    abandonedUpload := uploadTempMedia(t, access)
+    postUpload(abandonedUpload, []byte("nobody wants me"))
+    abandonedKey := abandonedUpload.FileID
+
+    attachedUpload := uploadTempMedia(t, access)
+    postUpload(attachedUpload, []byte("attached"))
+    articleUUID := CreateArticleWithMedia(t, access, "Keeps media", "content", []string{attachedUpload.FileID}, 201)
+
+    time.Sleep(11 * time.Second)
+
+    req, _ := http.NewRequest("POST", apiBase+"/admin/media/sweeper/run", nil)
+    req.Header.Set("Authorization", "Bearer "+access)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("Sweep failed: %v", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != 200 {
+        t.Fatalf("Sweep: expected 200, got %d", resp.StatusCode)
+    }
+    var stats struct {
+        Deleted int `json:"deleted"`
+    }
+    json.NewDecoder(resp.Body).Decode(&stats)
+    if stats.Deleted < 1 {
+        t.Errorf("Sweep: expected at least one deleted object")
+    }
+
+    if status := mediaStatus(t, staticURL(t, access, abandonedKey)); status != 404 {
+        t.Errorf("Abandoned upload still available, status %d", status)
+    }
+    if status := mediaStatus(t, GetArticle(t, articleUUID, 200).Media[0].S3Key); status != 200 {
+        t.Errorf("Attached media was swept, status %d", status)
+    }
+
+    // Статистика доступна администратору
+    req, _ = http.NewRequest("GET", apiBase+"/admin/media/sweeper", nil)
+    req.Header.Set("Authorization", "Bearer "+access)
+    statusResp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("Sweeper status failed: %v", err)
+    }
+    defer statusResp.Body.Close()
+    var status struct {
+        Runs         int `json:"runs"`
+        TotalDeleted int `json:"total_deleted"`
+    }
+    json.NewDecoder(statusResp.Body).Decode(&status)
+    if status.Runs < 1 || status.TotalDeleted < stats.Deleted {
+        t.Errorf("Sweeper status not updated: %+v", status)
+    }
+}
+
+func staticURL(t *testing.T, access, fileKey string) string {
+    req, _ := http.NewRequest("GET", apiBase+"/media/gen_static_get?uuid="+fileKey, nil)
+    req.Header.Set("Authorization", "Bearer "+access)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("gen_static_get failed: %v", err)
+    }
+    defer resp.Body.Close()
+    var out struct {
+        StaticURL string `json:"static_url"`
+    }
+    json.NewDecoder(resp.Body).Decode(&out)
+    return out.StaticURL
+}
+
+func mediaStatus(t *testing.T, url string) int {
+    resp, err := http.Get(url)
+    if err != nil {
+        t.Fatalf("Failed to get media file: %v", err)
+    }
+    resp.Body.Close()
+    return resp.StatusCode
+}
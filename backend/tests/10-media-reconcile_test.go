@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type ReconcileReport struct {
+	Unreferenced []string `json:"unreferenced"`
+	DanglingRows []struct {
+		S3Key string `json:"s3_key"`
+	} `json:"dangling_rows"`
+}
+
+func TestReplacedMediaIsReleased(t *testing.T) {
+    // Требует MEDIA_TEMP_TTL=10 на бэкенде (см. test.docker-compose.yml)
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    oldUpload := uploadTempMedia(t, access)
+    postUpload(oldUpload, []byte("old media"))
+    oldKey := oldUpload.FileID
+    articleUUID := CreateArticleWithMedia(t, access, "Replace media", "content", []string{oldKey}, 201)
+    oldMediaURL := GetArticle(t, articleUUID, 200).Media[0].S3Key
+
+    newUpload := uploadTempMedia(t, access)
+    postUpload(newUpload, []byte("new media"))
+    newKey := newUpload.FileID
+
+    body := map[string]interface{}{"media": []string{newKey}}
+    b, _ := json.Marshal(body)
+    req, _ := http.NewRequest("PUT", apiBase+"/articles/"+articleUUID, bytes.NewReader(b))
+    req.Header.Set("Authorization", "Bearer "+access)
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("UpdateArticle failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 200 {
+        t.Fatalf("UpdateArticle: expected 200, got %d", resp.StatusCode)
+    }
+
+    // Замененный файл понижен до временного, текущий используется статьей
+    report := ReconcileMedia(t, access, "report")
+    for _, key := range report.Unreferenced {
+        if key == oldKey || key == newKey {
+            t.Errorf("Reconcile: %s reported as unreferenced", key)
+        }
+    }
+    for _, row := range report.DanglingRows {
+        if row.S3Key == newKey {
+            t.Errorf("Reconcile: %s reported as dangling", newKey)
+        }
+    }
+
+    // После TTL уборщик удаляет замененный файл
+    time.Sleep(11 * time.Second)
+    req, _ = http.NewRequest("POST", apiBase+"/admin/media/sweeper/run", nil)
+    req.Header.Set("Authorization", "Bearer "+access)
+    resp, err = http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("Sweep failed: %v", err)
+    }
+    resp.Body.Close()
+
+    if status := mediaStatus(t, oldMediaURL); status != 404 {
+        t.Errorf("Replaced media still available, status %d", status)
+    }
+    if status := mediaStatus(t, GetArticle(t, articleUUID, 200).Media[0].S3Key); status != 200 {
+        t.Errorf("Current media was removed, status %d", status)
+    }
+}
+
+func ReconcileMedia(t *testing.T, access, mode string) ReconcileReport {
+    b, _ := json.Marshal(map[string]string{"mode": mode})
+    req, _ := http.NewRequest("POST", apiBase+"/admin/media/reconcile", bytes.NewReader(b))
+    req.Header.Set("Authorization", "Bearer "+access)
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("Reconcile failed: %v", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != 200 {
+        t.Fatalf("Reconcile: expected 200, got %d", resp.StatusCode)
+    }
+    var out ReconcileReport
+    json.NewDecoder(resp.Body).Decode(&out)
+    return out
+}
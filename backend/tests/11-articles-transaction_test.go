@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// Статья с несуществующим медиа не создается, а уже помеченные файлы откатываются
+func TestCreateArticleWithMissingMediaRollsBack(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    upload := uploadTempMedia(t, access)
+    postUpload(upload, []byte("real media"))
+    realKey := upload.FileID
+    // Загрузка выдана, но файл так и не отправлен
+    missingKey := uploadTempMedia(t, access).FileID
+
+    CreateArticleWithMedia(t, access, "Half created", "content", []string{realKey, missingKey}, 404)
+
+    page := ListArticles(t, nil, 200)
+    if len(page.Articles) != 0 {
+        t.Fatalf("Failed create left %d articles behind", len(page.Articles))
+    }
+
+    // Тег файла возвращен: сверка не считает его брошенным постоянным объектом
+    report := ReconcileMedia(t, access, "report")
+    for _, key := range report.Unreferenced {
+        if key == realKey {
+            t.Errorf("Media %s stayed permanent after rollback", realKey)
+        }
+    }
+
+    // Файл можно прикрепить к новой статье
+    articleUUID := CreateArticleWithMedia(t, access, "Created", "content", []string{realKey}, 201)
+    if got := GetArticle(t, articleUUID, 200); len(got.Media) != 1 {
+        t.Errorf("Expected 1 media, got %d", len(got.Media))
+    }
+}
+
+// Неудачное обновление не меняет ни текст, ни медиа статьи
+func TestUpdateArticleWithMissingMediaRollsBack(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    oldUpload := uploadTempMedia(t, access)
+    postUpload(oldUpload, []byte("old media"))
+    oldKey := oldUpload.FileID
+    articleUUID := CreateArticleWithMedia(t, access, "Stable", "original", []string{oldKey}, 201)
+
+    newUpload := uploadTempMedia(t, access)
+    postUpload(newUpload, []byte("new media"))
+    newKey := newUpload.FileID
+
+    body := map[string]interface{}{
+        "content": "changed",
+        "media":   []string{newKey, uploadTempMedia(t, access).FileID},
+    }
+    b, _ := json.Marshal(body)
+    req, _ := http.NewRequest("PUT", apiBase+"/articles/"+articleUUID, bytes.NewReader(b))
+    req.Header.Set("Authorization", "Bearer "+access)
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("UpdateArticle failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 404 {
+        t.Fatalf("UpdateArticle: expected 404, got %d", resp.StatusCode)
+    }
+
+    got := GetArticle(t, articleUUID, 200)
+    if got.Content != "original" {
+        t.Errorf("Failed update changed content to %q", got.Content)
+    }
+    if len(got.Media) != 1 || extractFileKeyFromURL(got.Media[0].S3Key) != oldKey {
+        t.Errorf("Failed update changed media: %+v", got.Media)
+    }
+    if status := mediaStatus(t, got.Media[0].S3Key); status != 200 {
+        t.Errorf("Old media not available after failed update, status %d", status)
+    }
+    if revisions := ListRevisions(t, articleUUID); len(revisions) != 1 {
+        t.Errorf("Failed update recorded a revision")
+    }
+
+    report := ReconcileMedia(t, access, "report")
+    for _, key := range report.Unreferenced {
+        if key == newKey || key == oldKey {
+            t.Errorf("Media %s left permanent and unreferenced", key)
+        }
+    }
+}
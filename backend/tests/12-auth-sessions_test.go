@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRefreshTokenRotation(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    _, first := LoginUser(t, username, password)
+
+    status, second := RefreshSession(t, first)
+    if status != 200 || second == "" || second == first {
+        t.Fatalf("Refresh: expected 200 and a rotated token, got %d", status)
+    }
+    status, third := RefreshSession(t, second)
+    if status != 200 || third == "" {
+        t.Fatalf("Refresh with rotated token: expected 200, got %d", status)
+    }
+
+    // Повторное использование старого токена отзывает всю сессию
+    time.Sleep(11 * time.Second)
+    if status, _ := RefreshSession(t, first); status != 401 {
+        t.Errorf("Reused token: expected 401, got %d", status)
+    }
+    if status, _ := RefreshSession(t, third); status != 401 {
+        t.Errorf("Token of revoked family: expected 401, got %d", status)
+    }
+
+    // Другие входы пользователя не затронуты
+    _, other := LoginUser(t, username, password)
+    if status, _ := RefreshSession(t, other); status != 200 {
+        t.Errorf("New session: expected 200, got %d", status)
+    }
+}
+
+func TestLogout(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    _, refresh := LoginUser(t, username, password)
+
+    req, _ := http.NewRequest("POST", apiBase+"/auth/logout", nil)
+    req.AddCookie(&http.Cookie{Name: "refresh_token", Value: refresh})
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("Logout failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 200 {
+        t.Fatalf("Logout: expected 200, got %d", resp.StatusCode)
+    }
+    cleared := false
+    for _, cookie := range resp.Cookies() {
+        if cookie.Name == "refresh_token" && cookie.MaxAge < 0 {
+            cleared = true
+        }
+    }
+    if !cleared {
+        t.Errorf("Logout: refresh cookie not cleared")
+    }
+
+    if status, _ := RefreshSession(t, refresh); status != 401 {
+        t.Errorf("Refresh after logout: expected 401, got %d", status)
+    }
+}
+
+func TestLogoutAll(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    _, laptop := LoginUser(t, username, password)
+    access, phone := LoginUser(t, username, password)
+
+    req, _ := http.NewRequest("POST", apiBase+"/auth/logout-all", nil)
+    req.Header.Set("Authorization", "Bearer "+access)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("Logout-all failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 200 {
+        t.Fatalf("Logout-all: expected 200, got %d", resp.StatusCode)
+    }
+
+    for _, refresh := range []string{laptop, phone} {
+        if status, _ := RefreshSession(t, refresh); status != 401 {
+            t.Errorf("Refresh after logout-all: expected 401, got %d", status)
+        }
+    }
+}
+
+// RefreshSession вызывает /auth/refresh с указанным токеном и возвращает статус
+// и новый refresh_token из cookie
+func RefreshSession(t *testing.T, refresh string) (int, string) {
+    req, _ := http.NewRequest("POST", apiBase+"/auth/refresh", nil)
+    req.AddCookie(&http.Cookie{Name: "refresh_token", Value: refresh})
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("Refresh failed: %v", err)
+    }
+    defer resp.Body.Close()
+    for _, cookie := range resp.Cookies() {
+        if cookie.Name == "refresh_token" && cookie.Value != "" {
+            return resp.StatusCode, cookie.Value
+        }
+    }
+    return resp.StatusCode, ""
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+type Session struct {
+    ID        string `json:"id"`
+    UserAgent string `json:"user_agent"`
+    IP        string `json:"ip"`
+    Current   bool   `json:"current"`
+}
+
+func TestListSessions(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    LoginUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    sessions := ListSessions(t, access, 200)
+    if len(sessions) != 2 {
+        t.Fatalf("Expected 2 sessions, got %d", len(sessions))
+    }
+    current := 0
+    for _, s := range sessions {
+        if s.Current {
+            current++
+        }
+        if s.UserAgent == "" || s.IP == "" {
+            t.Errorf("Session %s has no device metadata: %+v", s.ID, s)
+        }
+    }
+    if current != 1 {
+        t.Errorf("Expected exactly one current session, got %d", current)
+    }
+}
+
+func TestRevokeSession(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    otherAccess, otherRefresh := LoginUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    var other string
+    for _, s := range ListSessions(t, access, 200) {
+        if !s.Current {
+            other = s.ID
+        }
+    }
+    if other == "" {
+        t.Fatalf("Second session not listed")
+    }
+
+    // Чужую сессию отозвать нельзя
+    stranger, strangerPassword := UniqueUser()
+    RegisterUser(t, stranger, strangerPassword)
+    strangerAccess, _ := LoginUser(t, stranger, strangerPassword)
+    RevokeSession(t, strangerAccess, other, 404)
+
+    RevokeSession(t, access, other, 200)
+    RevokeSession(t, access, other, 404)
+
+    // Access и refresh токены отозванной сессии больше не принимаются
+    ListSessions(t, otherAccess, 401)
+    if status, _ := RefreshSession(t, otherRefresh); status != 401 {
+        t.Errorf("Refresh of revoked session: expected 401, got %d", status)
+    }
+
+    sessions := ListSessions(t, access, 200)
+    if len(sessions) != 1 || !sessions[0].Current {
+        t.Errorf("Expected only the current session to remain, got %+v", sessions)
+    }
+}
+
+func TestLogoutAllRejectsAccessTokens(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    req, _ := http.NewRequest("POST", apiBase+"/auth/logout-all", nil)
+    req.Header.Set("Authorization", "Bearer "+access)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("Logout-all failed: %v", err)
+    }
+    resp.Body.Close()
+
+    ListSessions(t, access, 401)
+}
+
+func ListSessions(t *testing.T, access string, wantStatus int) []Session {
+    req, _ := http.NewRequest("GET", apiBase+"/auth/sessions", nil)
+    req.Header.Set("Authorization", "Bearer "+access)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("List sessions failed: %v", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != wantStatus {
+        t.Fatalf("List sessions: expected %d, got %d", wantStatus, resp.StatusCode)
+    }
+    var out struct {
+        Sessions []Session `json:"sessions"`
+    }
+    json.NewDecoder(resp.Body).Decode(&out)
+    return out.Sessions
+}
+
+func RevokeSession(t *testing.T, access, id string, wantStatus int) {
+    req, _ := http.NewRequest("DELETE", apiBase+"/auth/sessions/"+id, nil)
+    req.Header.Set("Authorization", "Bearer "+access)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("Revoke session failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != wantStatus {
+        t.Errorf("Revoke session: expected %d, got %d", wantStatus, resp.StatusCode)
+    }
+}
@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type JWK struct {
+    Kty string `json:"kty"`
+    Kid string `json:"kid"`
+    Alg string `json:"alg"`
+    Crv string `json:"crv"`
+    X   string `json:"x"`
+}
+
+func TestAccessTokenVerifiesWithJWKS(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    resp, err := http.Get(apiBase + "/.well-known/jwks.json")
+    if err != nil {
+        t.Fatalf("JWKS request failed: %v", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != 200 {
+        t.Fatalf("JWKS: expected 200, got %d", resp.StatusCode)
+    }
+    var jwks struct {
+        Keys []JWK `json:"keys"`
+    }
+    json.NewDecoder(resp.Body).Decode(&jwks)
+    if len(jwks.Keys) == 0 {
+        t.Fatalf("JWKS has no keys")
+    }
+
+    // Проверяем подпись только публичным ключом, выбранным по kid
+    token, err := jwt.Parse(access, func(token *jwt.Token) (interface{}, error) {
+        kid, _ := token.Header["kid"].(string)
+        for _, key := range jwks.Keys {
+            if key.Kid == kid && key.Kty == "OKP" && token.Method.Alg() == key.Alg {
+                x, err := base64.RawURLEncoding.DecodeString(key.X)
+                return ed25519.PublicKey(x), err
+            }
+        }
+        t.Skipf("Token key %q is not an Ed25519 key from JWKS", kid)
+        return nil, nil
+    })
+    if err != nil || !token.Valid {
+        t.Fatalf("Access token does not verify with JWKS: %v", err)
+    }
+}
+
+func TestForgedAccessTokenRejected(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    // Токен с верными claims, подписанный HMAC, не должен приниматься
+    parsed, _, err := jwt.NewParser().ParseUnverified(access, jwt.MapClaims{})
+    if err != nil {
+        t.Fatalf("Parse access token: %v", err)
+    }
+    forged := jwt.NewWithClaims(jwt.SigningMethodHS256, parsed.Claims)
+    forged.Header["kid"] = parsed.Header["kid"]
+    forgedString, _ := forged.SignedString([]byte("guessed_secret"))
+
+    ListSessions(t, forgedString, 401)
+    ListSessions(t, access, 200)
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type AccessToken struct {
+    ID     string   `json:"id"`
+    Name   string   `json:"name"`
+    Prefix string   `json:"prefix"`
+    Scopes []string `json:"scopes"`
+    Token  string   `json:"token"`
+}
+
+func TestPersonalAccessToken(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    token := CreateAccessToken(t, access, "ci", []string{"articles:write"}, 201)
+    if !strings.HasPrefix(token.Token, "rhp_") || !strings.HasPrefix(token.Token, token.Prefix) {
+        t.Fatalf("Unexpected token format: %+v", token)
+    }
+
+    // Токен работает на маршрутах со своим scope
+    CreateArticle(t, token.Token, "Published from CI", "Some content", 201)
+
+    // Без нужного scope - 403, на маршрутах только для сессий - 401
+    req, _ := http.NewRequest("POST", apiBase+"/media/upload-temp", nil)
+    req.Header.Set("Authorization", "Bearer "+token.Token)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("Upload-temp failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 403 {
+        t.Errorf("Upload without media:write scope: expected 403, got %d", resp.StatusCode)
+    }
+    ListSessions(t, token.Token, 401)
+
+    // Сам токен в списке не возвращается
+    tokens := ListAccessTokens(t, access)
+    if len(tokens) != 1 || tokens[0].Token != "" || tokens[0].Name != "ci" {
+        t.Fatalf("Unexpected token list: %+v", tokens)
+    }
+
+    RevokeAccessToken(t, access, token.ID, 200)
+    RevokeAccessToken(t, access, token.ID, 404)
+    CreateArticle(t, token.Token, "After revoke", "Some content", 401)
+}
+
+func TestAccessTokenScopeLimitedByRole(t *testing.T) {
+    ResetDB(t)
+    adminName, adminPassword := UniqueNamedUser("admin")
+    RegisterUser(t, adminName, adminPassword)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    CreateAccessToken(t, access, "admin", []string{"admin"}, 403)
+    CreateAccessToken(t, access, "bad", []string{"everything"}, 400)
+    CreateAccessToken(t, access, "", []string{"articles:read"}, 400)
+
+    // Права токена следуют текущей роли владельца
+    token := CreateAccessToken(t, access, "ci", []string{"articles:write"}, 201)
+    adminAccess, _ := LoginUser(t, adminName, adminPassword)
+    SetUserRole(t, adminAccess, username, "reader", 200)
+    CreateArticle(t, token.Token, "Demoted", "Some content", 403)
+}
+
+func CreateAccessToken(t *testing.T, access, name string, scopes []string, wantStatus int) AccessToken {
+    b, _ := json.Marshal(map[string]interface{}{"name": name, "scopes": scopes, "expires_in_days": 7})
+    req, _ := http.NewRequest("POST", apiBase+"/auth/tokens", bytes.NewReader(b))
+    req.Header.Set("Authorization", "Bearer "+access)
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("Create token failed: %v", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != wantStatus {
+        t.Fatalf("Create token: expected %d, got %d", wantStatus, resp.StatusCode)
+    }
+    var out AccessToken
+    json.NewDecoder(resp.Body).Decode(&out)
+    return out
+}
+
+func ListAccessTokens(t *testing.T, access string) []AccessToken {
+    req, _ := http.NewRequest("GET", apiBase+"/auth/tokens", nil)
+    req.Header.Set("Authorization", "Bearer "+access)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("List tokens failed: %v", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != 200 {
+        t.Fatalf("List tokens: expected 200, got %d", resp.StatusCode)
+    }
+    var out struct {
+        Tokens []AccessToken `json:"tokens"`
+    }
+    json.NewDecoder(resp.Body).Decode(&out)
+    return out.Tokens
+}
+
+func RevokeAccessToken(t *testing.T, access, id string, wantStatus int) {
+    req, _ := http.NewRequest("DELETE", apiBase+"/auth/tokens/"+id, nil)
+    req.Header.Set("Authorization", "Bearer "+access)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("Revoke token failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != wantStatus {
+        t.Errorf("Revoke token: expected %d, got %d", wantStatus, resp.StatusCode)
+    }
+}
@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"rulehub/utils"
+)
+
+func TestTwoFactorLogin(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    var enroll struct {
+        Secret     string `json:"secret"`
+        OTPAuthURI string `json:"otpauth_uri"`
+    }
+    PostJSON(t, access, "/auth/2fa/enroll", nil, 200, &enroll)
+    if enroll.Secret == "" || enroll.OTPAuthURI == "" {
+        t.Fatalf("Enrollment returned no secret: %+v", enroll)
+    }
+    key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enroll.Secret)
+    if err != nil {
+        t.Fatalf("Secret is not base32: %v", err)
+    }
+    code := func(offset time.Duration) string {
+        return utils.TOTP(key, time.Now().Add(offset), utils.TOTPPeriod, utils.TOTPDigits, sha1.New)
+    }
+
+    PostJSON(t, access, "/auth/2fa/confirm", map[string]string{"code": "000000x"}, 400, nil)
+    wrong := "000000"
+    if wrong == code(0) || wrong == code(-30*time.Second) || wrong == code(30*time.Second) {
+        wrong = "999999"
+    }
+    PostJSON(t, access, "/auth/2fa/confirm", map[string]string{"code": wrong}, 401, nil)
+    var recovery struct {
+        RecoveryCodes []string `json:"recovery_codes"`
+    }
+    PostJSON(t, access, "/auth/2fa/confirm", map[string]string{"code": code(0)}, 200, &recovery)
+    if len(recovery.RecoveryCodes) != 10 {
+        t.Fatalf("Expected 10 recovery codes, got %d", len(recovery.RecoveryCodes))
+    }
+
+    // Пароль дает только challenge, токены выдаются после второго шага
+    challenge := LoginChallenge(t, username, password)
+    PostJSON(t, "", "/auth/login/2fa", map[string]string{"challenge_token": challenge, "code": "123456"}, 401, nil)
+    // Код текущего периода уже использован при подтверждении, берем следующий
+    next := code(30 * time.Second)
+    var signIn struct {
+        AccessToken string `json:"access_token"`
+    }
+    PostJSON(t, "", "/auth/login/2fa", map[string]string{"challenge_token": challenge, "code": next}, 200, &signIn)
+    if signIn.AccessToken == "" {
+        t.Fatalf("Second step returned no access token")
+    }
+    // Повторно тот же код не принимается
+    PostJSON(t, "", "/auth/login/2fa", map[string]string{"challenge_token": LoginChallenge(t, username, password), "code": next}, 401, nil)
+
+    // Код восстановления одноразовый
+    PostJSON(t, "", "/auth/login/2fa", map[string]string{"challenge_token": LoginChallenge(t, username, password), "code": recovery.RecoveryCodes[0]}, 200, nil)
+    PostJSON(t, "", "/auth/login/2fa", map[string]string{"challenge_token": LoginChallenge(t, username, password), "code": recovery.RecoveryCodes[0]}, 401, nil)
+
+    var status struct {
+        Enabled           bool `json:"enabled"`
+        RecoveryCodesLeft int  `json:"recovery_codes_left"`
+    }
+    GetJSONAuth(t, signIn.AccessToken, "/auth/2fa", 200, &status)
+    if !status.Enabled || status.RecoveryCodesLeft != 9 {
+        t.Errorf("Unexpected 2FA status: %+v", status)
+    }
+
+    // Отключение требует пароль и второй фактор
+    PostJSON(t, signIn.AccessToken, "/auth/2fa/disable", map[string]string{"password": "wrong123", "code": recovery.RecoveryCodes[1]}, 401, nil)
+    PostJSON(t, signIn.AccessToken, "/auth/2fa/disable", map[string]string{"password": password, "code": recovery.RecoveryCodes[1]}, 200, nil)
+    LoginUser(t, username, password)
+}
+
+func TestTwoFactorChallengeIsNotAnAccessToken(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    var enroll struct {
+        Secret string `json:"secret"`
+    }
+    PostJSON(t, access, "/auth/2fa/enroll", nil, 200, &enroll)
+    key, _ := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enroll.Secret)
+    PostJSON(t, access, "/auth/2fa/confirm", map[string]string{"code": utils.TOTP(key, time.Now(), utils.TOTPPeriod, utils.TOTPDigits, sha1.New)}, 200, nil)
+
+    challenge := LoginChallenge(t, username, password)
+    ListSessions(t, challenge, 401)
+    if status, _ := RefreshSession(t, challenge); status != 401 {
+        t.Errorf("Challenge as refresh token: expected 401, got %d", status)
+    }
+}
+
+// LoginChallenge выполняет первый шаг входа для аккаунта с 2FA
+func LoginChallenge(t *testing.T, username, password string) string {
+    var out struct {
+        TwoFactorRequired bool   `json:"two_factor_required"`
+        ChallengeToken    string `json:"challenge_token"`
+        AccessToken       string `json:"access_token"`
+    }
+    PostJSON(t, "", "/auth/login", map[string]string{"username": username, "password": password}, 200, &out)
+    if !out.TwoFactorRequired || out.ChallengeToken == "" || out.AccessToken != "" {
+        t.Fatalf("Expected a 2FA challenge, got %+v", out)
+    }
+    return out.ChallengeToken
+}
+
+func PostJSON(t *testing.T, access, path string, body interface{}, wantStatus int, out interface{}) {
+    var reader *bytes.Reader
+    if body != nil {
+        b, _ := json.Marshal(body)
+        reader = bytes.NewReader(b)
+    } else {
+        reader = bytes.NewReader(nil)
+    }
+    req, _ := http.NewRequest("POST", apiBase+path, reader)
+    req.Header.Set("Content-Type", "application/json")
+    if access != "" {
+        req.Header.Set("Authorization", "Bearer "+access)
+    }
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("POST %s failed: %v", path, err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != wantStatus {
+        t.Fatalf("POST %s: expected %d, got %d", path, wantStatus, resp.StatusCode)
+    }
+    if out != nil {
+        json.NewDecoder(resp.Body).Decode(out)
+    }
+}
+
+func GetJSONAuth(t *testing.T, access, path string, wantStatus int, out interface{}) {
+    req, _ := http.NewRequest("GET", apiBase+path, nil)
+    req.Header.Set("Authorization", "Bearer "+access)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("GET %s failed: %v", path, err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != wantStatus {
+        t.Fatalf("GET %s: expected %d, got %d", path, wantStatus, resp.StatusCode)
+    }
+    if out != nil {
+        json.NewDecoder(resp.Body).Decode(out)
+    }
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type Invite struct {
+    ID      string `json:"id"`
+    Code    string `json:"code"`
+    Role    string `json:"role"`
+    MaxUses int    `json:"max_uses"`
+    Uses    int    `json:"uses"`
+}
+
+func TestInviteRegistration(t *testing.T) {
+    ResetDB(t)
+    adminName, adminPassword := UniqueNamedUser("admin")
+    RegisterUser(t, adminName, adminPassword)
+    adminAccess, _ := LoginUser(t, adminName, adminPassword)
+
+    var invite Invite
+    PostJSON(t, adminAccess, "/admin/invites", map[string]interface{}{"role": "editor", "max_uses": 2}, 201, &invite)
+    if invite.Code == "" || invite.Role != "editor" || invite.MaxUses != 2 {
+        t.Fatalf("Unexpected invite: %+v", invite)
+    }
+
+    // Приглашение выдает свою роль и расходуется
+    first, password := UniqueNamedUser("invited")
+    RegisterWithInvite(t, first, password, invite.Code, 201)
+    access, _ := LoginUser(t, first, password)
+    if role := tokenRole(t, access); role != "editor" {
+        t.Errorf("Invited user role: expected editor, got %s", role)
+    }
+    second, _ := UniqueNamedUser("invited")
+    RegisterWithInvite(t, second, password, invite.Code, 201)
+    third, _ := UniqueNamedUser("invited")
+    RegisterWithInvite(t, third, password, invite.Code, 403)
+
+    RegisterWithInvite(t, third, password, "no-such-invite", 403)
+
+    // Отозванное приглашение не принимается
+    var revoked Invite
+    PostJSON(t, adminAccess, "/admin/invites", map[string]interface{}{"role": "reader"}, 201, &revoked)
+    req, _ := http.NewRequest("DELETE", apiBase+"/admin/invites/"+revoked.ID, nil)
+    req.Header.Set("Authorization", "Bearer "+adminAccess)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("Revoke invite failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 200 {
+        t.Fatalf("Revoke invite: expected 200, got %d", resp.StatusCode)
+    }
+    RegisterWithInvite(t, third, password, revoked.Code, 403)
+
+    var list struct {
+        Invites []Invite `json:"invites"`
+    }
+    GetJSONAuth(t, adminAccess, "/admin/invites", 200, &list)
+    if len(list.Invites) != 2 {
+        t.Fatalf("Expected 2 invites, got %d", len(list.Invites))
+    }
+    for _, inv := range list.Invites {
+        if inv.Code != "" {
+            t.Errorf("Invite list exposes the code")
+        }
+        if inv.ID == invite.ID && inv.Uses != 2 {
+            t.Errorf("Invite uses: expected 2, got %d", inv.Uses)
+        }
+    }
+
+    // Только администратор выпускает приглашения
+    PostJSON(t, access, "/admin/invites", map[string]interface{}{"role": "admin"}, 403, nil)
+}
+
+func RegisterWithInvite(t *testing.T, username, password, code string, wantStatus int) {
+    b, _ := json.Marshal(map[string]string{"username": username, "password": password, "invite_code": code})
+    resp, err := http.Post(apiBase+"/auth/register", "application/json", bytes.NewReader(b))
+    if err != nil {
+        t.Fatalf("Register failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != wantStatus {
+        t.Fatalf("Register with invite: expected %d, got %d", wantStatus, resp.StatusCode)
+    }
+}
+
+// tokenRole читает роль из payload access токена
+func tokenRole(t *testing.T, access string) string {
+    role, _ := tokenClaims(t, access)["role"].(string)
+    return role
+}
+
+// tokenClaims декодирует payload access токена без проверки подписи
+func tokenClaims(t *testing.T, access string) map[string]interface{} {
+    parts := strings.Split(access, ".")
+    if len(parts) != 3 {
+        t.Fatalf("Malformed access token")
+    }
+    payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+    if err != nil {
+        t.Fatalf("Malformed access token payload: %v", err)
+    }
+    claims := map[string]interface{}{}
+    json.Unmarshal(payload, &claims)
+    return claims
+}
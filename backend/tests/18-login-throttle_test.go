@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLoginLockout(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+
+    // Бесплатные попытки (LOGIN_MAX_ATTEMPTS=5) и первая блокирующая ошибка
+    for i := 0; i < 6; i++ {
+        if status, _ := loginAttempt(t, username, "wrongpass123"); status != 401 {
+            t.Fatalf("Wrong password #%d: expected 401, got %d", i+1, status)
+        }
+    }
+
+    // Во время блокировки не проходит даже верный пароль
+    status, retryAfter := loginAttempt(t, username, password)
+    if status != 429 {
+        t.Fatalf("Locked login: expected 429, got %d", status)
+    }
+    wait, err := strconv.Atoi(retryAfter)
+    if err != nil || wait <= 0 {
+        t.Fatalf("Locked login: bad Retry-After %q", retryAfter)
+    }
+
+    // Следующая ошибка после блокировки удваивает ее
+    time.Sleep(time.Duration(wait) * time.Second)
+    loginAttempt(t, username, "wrongpass123")
+    if _, retryAfter := loginAttempt(t, username, password); retryAfter != "2" {
+        t.Errorf("Second lockout: expected Retry-After 2, got %q", retryAfter)
+    }
+
+    // После блокировки верный пароль принимается и сбрасывает счетчик
+    time.Sleep(2 * time.Second)
+    if status, _ := loginAttempt(t, username, password); status != 200 {
+        t.Fatalf("Login after lockout: expected 200, got %d", status)
+    }
+    if status, _ := loginAttempt(t, username, "wrongpass123"); status != 401 {
+        t.Errorf("Wrong password after reset: expected 401, got %d", status)
+    }
+}
+
+func TestLockoutIsPerUsername(t *testing.T) {
+    ResetDB(t)
+    victim, _ := UniqueNamedUser("victim")
+    username, password := UniqueNamedUser("other")
+    RegisterUser(t, victim, "password123")
+    RegisterUser(t, username, password)
+
+    for i := 0; i < 6; i++ {
+        loginAttempt(t, victim, "wrongpass123")
+    }
+    if status, _ := loginAttempt(t, victim, "password123"); status != 429 {
+        t.Fatalf("Locked user: expected 429, got %d", status)
+    }
+    LoginUser(t, username, password)
+}
+
+func TestSpoofedForwardedFor(t *testing.T) {
+    ResetDB(t)
+    t.Cleanup(func() { ResetDB(t) })
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+
+    // Блокировка по IP (LOGIN_IP_MAX_ATTEMPTS=1000) не обходится подменой
+    // X-Forwarded-For и X-Real-IP в каждом запросе
+    status := 0
+    for i := 0; i < 1100 && status != 429; i++ {
+        status, _ = spoofedLoginAttempt(t, fmt.Sprintf("nobody_%d", i), "wrongpass123", i)
+    }
+    if status != 429 {
+        t.Fatalf("IP lockout: expected 429, got %d", status)
+    }
+    if status, _ := spoofedLoginAttempt(t, username, password, 5000); status != 429 {
+        t.Errorf("Locked IP with a new forwarded address: expected 429, got %d", status)
+    }
+}
+
+// spoofedLoginAttempt входит с заголовками, притворяющимися прокси с
+// адресом клиента, зависящим от n
+func spoofedLoginAttempt(t *testing.T, username, password string, n int) (int, string) {
+    b, _ := json.Marshal(map[string]string{"username": username, "password": password})
+    req, _ := http.NewRequest("POST", apiBase+"/auth/login", bytes.NewReader(b))
+    req.Header.Set("Content-Type", "application/json")
+    fake := fmt.Sprintf("203.0.%d.%d", n/250%250, n%250+1)
+    req.Header.Set("X-Forwarded-For", fake)
+    req.Header.Set("X-Real-IP", fake)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("Login failed: %v", err)
+    }
+    defer resp.Body.Close()
+    return resp.StatusCode, resp.Header.Get("Retry-After")
+}
+
+func TestRateLimit(t *testing.T) {
+    // В тестовом окружении RATE_LIMIT_WELLKNOWN=1,3
+    limited := false
+    for i := 0; i < 10 && !limited; i++ {
+        resp, err := http.Get(apiBase + "/.well-known/jwks.json")
+        if err != nil {
+            t.Fatalf("JWKS request failed: %v", err)
+        }
+        resp.Body.Close()
+        switch resp.StatusCode {
+        case 200:
+        case 429:
+            limited = true
+            if resp.Header.Get("Retry-After") == "" {
+                t.Errorf("429 without Retry-After")
+            }
+        default:
+            t.Fatalf("JWKS: unexpected status %d", resp.StatusCode)
+        }
+    }
+    if !limited {
+        t.Fatalf("Expected 429 after a burst of requests")
+    }
+
+    // Подмена адреса в заголовках не дает новый лимит
+    req, _ := http.NewRequest("GET", apiBase+"/.well-known/jwks.json", nil)
+    req.Header.Set("X-Forwarded-For", "198.51.100.7")
+    req.Header.Set("X-Real-IP", "198.51.100.7")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("JWKS request failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 429 {
+        t.Errorf("JWKS with a spoofed address: expected 429, got %d", resp.StatusCode)
+    }
+
+    time.Sleep(time.Second)
+    resp, err = http.Get(apiBase + "/.well-known/jwks.json")
+    if err != nil {
+        t.Fatalf("JWKS request failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 200 {
+        t.Errorf("JWKS after refill: expected 200, got %d", resp.StatusCode)
+    }
+}
+
+// loginAttempt возвращает статус входа и заголовок Retry-After
+func loginAttempt(t *testing.T, username, password string) (int, string) {
+    b, _ := json.Marshal(map[string]string{"username": username, "password": password})
+    resp, err := http.Post(apiBase+"/auth/login", "application/json", bytes.NewReader(b))
+    if err != nil {
+        t.Fatalf("Login failed: %v", err)
+    }
+    defer resp.Body.Close()
+    return resp.StatusCode, resp.Header.Get("Retry-After")
+}
@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"testing"
+)
+
+func TestPasswordChange(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, current := LoginUser(t, username, password)
+    _, other := LoginUser(t, username, password)
+
+    PostJSON(t, access, "/auth/password", map[string]string{"current_password": "wrongpass123", "new_password": "newpass456"}, 403, nil)
+    // Новый пароль проверяется тем же валидатором, что и при регистрации
+    PostJSON(t, access, "/auth/password", map[string]string{"current_password": password, "new_password": "NOLOWER123"}, 400, nil)
+    PostJSON(t, access, "/auth/password", map[string]string{"current_password": password, "new_password": "newpass456"}, 200, nil)
+
+    // Остальные сессии отозваны, текущая продолжает работать
+    if status, _ := RefreshSession(t, other); status != 401 {
+        t.Errorf("Other session: expected 401, got %d", status)
+    }
+    if status, _ := RefreshSession(t, current); status != 200 {
+        t.Errorf("Current session: expected 200, got %d", status)
+    }
+
+    LoginUserExpectStatus(t, username, password, 401)
+    LoginUser(t, username, "newpass456")
+}
+
+func TestAdminPasswordReset(t *testing.T) {
+    ResetDB(t)
+    adminName, adminPassword := UniqueNamedUser("admin")
+    RegisterUser(t, adminName, adminPassword)
+    adminAccess, _ := LoginUser(t, adminName, adminPassword)
+    username, password := UniqueNamedUser("forgetful")
+    RegisterUser(t, username, password)
+    access, refresh := LoginUser(t, username, password)
+
+    // Сбрасывать пароли может только администратор
+    PostJSON(t, access, "/admin/users/"+username+"/password-reset", nil, 403, nil)
+    PostJSON(t, adminAccess, "/admin/users/nosuchuser/password-reset", nil, 404, nil)
+
+    var first, reset struct {
+        Token string `json:"token"`
+    }
+    PostJSON(t, adminAccess, "/admin/users/"+username+"/password-reset", nil, 201, &first)
+    // Новый токен заменяет предыдущий
+    PostJSON(t, adminAccess, "/admin/users/"+username+"/password-reset", nil, 201, &reset)
+    if reset.Token == "" || reset.Token == first.Token {
+        t.Fatalf("Expected a new reset token, got %q", reset.Token)
+    }
+    PostJSON(t, "", "/auth/reset", map[string]string{"token": first.Token, "new_password": "newpass456"}, 403, nil)
+
+    PostJSON(t, "", "/auth/reset", map[string]string{"token": reset.Token, "new_password": "weak"}, 400, nil)
+    PostJSON(t, "", "/auth/reset", map[string]string{"token": reset.Token, "new_password": "newpass456"}, 200, nil)
+    // Токен одноразовый
+    PostJSON(t, "", "/auth/reset", map[string]string{"token": reset.Token, "new_password": "another789"}, 403, nil)
+
+    // Все сессии пользователя отозваны
+    if status, _ := RefreshSession(t, refresh); status != 401 {
+        t.Errorf("Session after reset: expected 401, got %d", status)
+    }
+    LoginUserExpectStatus(t, username, password, 401)
+    LoginUser(t, username, "newpass456")
+}
@@ -0,0 +1,259 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Требует mock-oidc из test.docker-compose.yml и OIDC_AUTO_PROVISION=true на бэкенде
+
+// noRedirectClient возвращает ответы с редиректами как есть
+var noRedirectClient = &http.Client{
+    CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+type Identity struct {
+    ID      string `json:"id"`
+    Subject string `json:"subject"`
+    Email   string `json:"email"`
+}
+
+func TestOIDCLoginProvisionsUser(t *testing.T) {
+    ResetDB(t)
+    adminName, adminPassword := UniqueNamedUser("admin")
+    RegisterUser(t, adminName, adminPassword)
+
+    subject := fmt.Sprintf("sso-%d", time.Now().UnixNano())
+    claims := url.Values{"sub": {subject}, "email": {"jane@example.com"}, "preferred_username": {"jane.doe"}}
+    result, refresh := OIDCLogin(t, oidcAuthorize(t, OIDCStart(t), claims))
+    if result.Get("oidc") != "ok" || refresh == "" {
+        t.Fatalf("OIDC login: expected success and a session, got %v", result)
+    }
+    access := accessFromRefresh(t, refresh)
+    if username := tokenClaims(t, access)["username"]; username != "janedoe" {
+        t.Errorf("Provisioned username: expected janedoe, got %v", username)
+    }
+    if role := tokenRole(t, access); role != "author" {
+        t.Errorf("Provisioned role: expected author, got %s", role)
+    }
+
+    // Повторный вход попадает в тот же аккаунт
+    result, refresh = OIDCLogin(t, oidcAuthorize(t, OIDCStart(t), claims))
+    if result.Get("oidc") != "ok" {
+        t.Fatalf("Second OIDC login failed: %v", result)
+    }
+    again := accessFromRefresh(t, refresh)
+    if tokenClaims(t, again)["user_id"] != tokenClaims(t, access)["user_id"] {
+        t.Errorf("Second OIDC login created another user")
+    }
+
+    // У такого аккаунта нет локального пароля, единственную привязку удалить нельзя
+    var identities struct {
+        Identities []Identity `json:"identities"`
+    }
+    GetJSONAuth(t, access, "/auth/oidc/identities", 200, &identities)
+    if len(identities.Identities) != 1 || identities.Identities[0].Subject != subject {
+        t.Fatalf("Unexpected identities: %+v", identities)
+    }
+    deleteAuth(t, access, "/auth/oidc/identities/"+identities.Identities[0].ID, 409)
+}
+
+func TestOIDCCallbackChecksState(t *testing.T) {
+    ResetDB(t)
+    subject := fmt.Sprintf("sso-%d", time.Now().UnixNano())
+
+    // Callback без cookie, выданной при старте входа, не принимается
+    start := OIDCStart(t)
+    callback := oidcAuthorize(t, start, url.Values{"sub": {subject}})
+    callback.cookie = nil
+    result, refresh := OIDCLogin(t, callback)
+    if result.Get("oidc_error") != "invalid_state" || refresh != "" {
+        t.Errorf("Callback without state cookie: expected invalid_state, got %v", result)
+    }
+
+    // state из другого входа тоже
+    other := OIDCStart(t)
+    result, _ = OIDCLogin(t, oidcAuthorize(t, oidcFlow{authURL: start.authURL, cookie: other.cookie}, url.Values{"sub": {subject}}))
+    if result.Get("oidc_error") != "invalid_state" {
+        t.Errorf("Callback with foreign state: expected invalid_state, got %v", result)
+    }
+
+    // Отказ пользователя у провайдера
+    result, _ = OIDCLogin(t, oidcAuthorize(t, OIDCStart(t), url.Values{"deny": {"1"}}))
+    if result.Get("oidc_error") != "provider_error" {
+        t.Errorf("Denied login: expected provider_error, got %v", result)
+    }
+}
+
+func TestOIDCLinkIdentity(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueNamedUser("local")
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+    subject := fmt.Sprintf("sso-%d", time.Now().UnixNano())
+
+    result, _ := OIDCLogin(t, oidcAuthorize(t, OIDCLinkStart(t, access), url.Values{"sub": {subject}, "email": {"local@example.com"}}))
+    if result.Get("oidc") != "linked" {
+        t.Fatalf("Link: expected oidc=linked, got %v", result)
+    }
+
+    // Теперь вход через провайдера открывает локальный аккаунт
+    result, refresh := OIDCLogin(t, oidcAuthorize(t, OIDCStart(t), url.Values{"sub": {subject}}))
+    if result.Get("oidc") != "ok" {
+        t.Fatalf("OIDC login of linked identity failed: %v", result)
+    }
+    if name := tokenClaims(t, accessFromRefresh(t, refresh))["username"]; name != username {
+        t.Errorf("Linked login: expected user %s, got %v", username, name)
+    }
+
+    // Чужую привязку перенести нельзя
+    otherName, otherPassword := UniqueNamedUser("other")
+    RegisterUser(t, otherName, otherPassword)
+    otherAccess, _ := LoginUser(t, otherName, otherPassword)
+    result, _ = OIDCLogin(t, oidcAuthorize(t, OIDCLinkStart(t, otherAccess), url.Values{"sub": {subject}}))
+    if result.Get("oidc_error") != "identity_in_use" {
+        t.Errorf("Linking a taken identity: expected identity_in_use, got %v", result)
+    }
+
+    // У пользователя с паролем привязку можно удалить
+    var identities struct {
+        Identities []Identity `json:"identities"`
+    }
+    GetJSONAuth(t, access, "/auth/oidc/identities", 200, &identities)
+    if len(identities.Identities) != 1 || identities.Identities[0].Email != "local@example.com" {
+        t.Fatalf("Unexpected identities: %+v", identities)
+    }
+    deleteAuth(t, otherAccess, "/auth/oidc/identities/"+identities.Identities[0].ID, 404)
+    deleteAuth(t, access, "/auth/oidc/identities/"+identities.Identities[0].ID, 200)
+}
+
+// oidcFlow - начатый вход: адрес провайдера и cookie со state
+type oidcFlow struct {
+    authURL string
+    cookie  *http.Cookie
+}
+
+// OIDCStart начинает вход через /auth/oidc/login
+func OIDCStart(t *testing.T) oidcFlow {
+    resp, err := noRedirectClient.Get(apiBase + "/auth/oidc/login")
+    if err != nil {
+        t.Fatalf("OIDC login start failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 302 {
+        t.Fatalf("OIDC login start: expected 302, got %d", resp.StatusCode)
+    }
+    return oidcFlow{authURL: resp.Header.Get("Location"), cookie: stateCookie(t, resp)}
+}
+
+// OIDCLinkStart начинает привязку identity к пользователю access
+func OIDCLinkStart(t *testing.T, access string) oidcFlow {
+    req, _ := http.NewRequest("POST", apiBase+"/auth/oidc/link", nil)
+    req.Header.Set("Authorization", "Bearer "+access)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("OIDC link start failed: %v", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != 200 {
+        t.Fatalf("OIDC link start: expected 200, got %d", resp.StatusCode)
+    }
+    var out struct {
+        AuthorizationURL string `json:"authorization_url"`
+    }
+    json.NewDecoder(resp.Body).Decode(&out)
+    return oidcFlow{authURL: out.AuthorizationURL, cookie: stateCookie(t, resp)}
+}
+
+func stateCookie(t *testing.T, resp *http.Response) *http.Cookie {
+    for _, cookie := range resp.Cookies() {
+        if cookie.Name == "oidc_state" {
+            return cookie
+        }
+    }
+    t.Fatalf("No oidc_state cookie")
+    return nil
+}
+
+// oidcCallback - адрес callback от провайдера и cookie браузера, начавшего вход
+type oidcCallback struct {
+    url    string
+    cookie *http.Cookie
+}
+
+// oidcAuthorize "входит" у mock провайдера с заданными claims
+func oidcAuthorize(t *testing.T, flow oidcFlow, claims url.Values) oidcCallback {
+    resp, err := noRedirectClient.Get(flow.authURL + "&" + claims.Encode())
+    if err != nil {
+        t.Fatalf("OIDC authorize failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 302 {
+        t.Fatalf("OIDC authorize: expected 302, got %d", resp.StatusCode)
+    }
+    return oidcCallback{url: resp.Header.Get("Location"), cookie: flow.cookie}
+}
+
+// OIDCLogin открывает callback и возвращает параметры редиректа на фронтенд
+// и refresh_token, если сессия создана
+func OIDCLogin(t *testing.T, callback oidcCallback) (url.Values, string) {
+    req, _ := http.NewRequest("GET", callback.url, nil)
+    if callback.cookie != nil {
+        req.AddCookie(callback.cookie)
+    }
+    resp, err := noRedirectClient.Do(req)
+    if err != nil {
+        t.Fatalf("OIDC callback failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 302 {
+        t.Fatalf("OIDC callback: expected 302, got %d", resp.StatusCode)
+    }
+    location, err := url.Parse(resp.Header.Get("Location"))
+    if err != nil {
+        t.Fatalf("OIDC callback: bad redirect: %v", err)
+    }
+    for _, c := range resp.Cookies() {
+        if c.Name == "refresh_token" && c.Value != "" {
+            return location.Query(), c.Value
+        }
+    }
+    return location.Query(), ""
+}
+
+// accessFromRefresh получает access токен сессии по refresh_token
+func accessFromRefresh(t *testing.T, refresh string) string {
+    req, _ := http.NewRequest("POST", apiBase+"/auth/refresh", nil)
+    req.AddCookie(&http.Cookie{Name: "refresh_token", Value: refresh})
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("Refresh failed: %v", err)
+    }
+    defer resp.Body.Close()
+    var out struct {
+        AccessToken string `json:"access_token"`
+    }
+    json.NewDecoder(resp.Body).Decode(&out)
+    if resp.StatusCode != 200 || !strings.Contains(out.AccessToken, ".") {
+        t.Fatalf("Refresh: expected 200 and an access token, got %d", resp.StatusCode)
+    }
+    return out.AccessToken
+}
+
+func deleteAuth(t *testing.T, access, path string, wantStatus int) {
+    req, _ := http.NewRequest("DELETE", apiBase+path, nil)
+    req.Header.Set("Authorization", "Bearer "+access)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("DELETE %s failed: %v", path, err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != wantStatus {
+        t.Fatalf("DELETE %s: expected %d, got %d", path, wantStatus, resp.StatusCode)
+    }
+}
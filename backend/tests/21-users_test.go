@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type Profile struct {
+    Username    string `json:"username"`
+    DisplayName string `json:"display_name"`
+    AvatarURL   string `json:"avatar_url"`
+    Bio         string `json:"bio"`
+    Role        string `json:"role"`
+}
+
+type UserPage struct {
+    Profile  Profile `json:"profile"`
+    Articles []struct {
+        UUID          string  `json:"id"`
+        AuthorProfile Profile `json:"author_profile"`
+    } `json:"articles"`
+    NextCursor string `json:"next_cursor"`
+}
+
+func TestUserProfileUpdate(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    var me Profile
+    GetJSONAuth(t, access, "/users/me", 200, &me)
+    if me.Username != username || me.DisplayName != "" || me.AvatarURL != "" {
+        t.Fatalf("Fresh profile: unexpected %+v", me)
+    }
+
+    // Меняются только переданные поля
+    PutProfile(t, access, map[string]interface{}{"display_name": "Jane", "bio": "Writes rules"}, 200, &me)
+    PutProfile(t, access, map[string]interface{}{"bio": "Edits rules"}, 200, &me)
+    if me.DisplayName != "Jane" || me.Bio != "Edits rules" {
+        t.Errorf("Partial update: unexpected %+v", me)
+    }
+    PutProfile(t, access, map[string]interface{}{"display_name": strings.Repeat("a", 65)}, 400, nil)
+
+    // Аватаром может быть только изображение
+    upload := uploadTempMedia(t, access)
+    resp, err := postUpload(upload, []byte("avatar"))
+    if err != nil || resp.StatusCode != 204 {
+        t.Fatalf("Avatar upload failed: %v", err)
+    }
+    resp.Body.Close()
+    PutProfile(t, access, map[string]interface{}{"avatar": upload.FileID}, 422, nil)
+
+    // Аватар загружается как обычный медиафайл
+    PutProfile(t, access, map[string]interface{}{"avatar": uploadImage(t, access, 8, 8)}, 200, &me)
+    if me.AvatarURL == "" || mediaStatus(t, me.AvatarURL) != 200 {
+        t.Fatalf("Avatar not available: %+v", me)
+    }
+    PutProfile(t, access, map[string]interface{}{"avatar": uploadTempMedia(t, access).FileID}, 404, nil)
+
+    // Пустая строка убирает аватар
+    PutProfile(t, access, map[string]interface{}{"avatar": ""}, 200, &me)
+    if me.AvatarURL != "" {
+        t.Errorf("Avatar not removed: %+v", me)
+    }
+
+    GetJSONAuth(t, "", "/users/me", 401, nil)
+}
+
+func TestUserPage(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+    PutProfile(t, access, map[string]interface{}{"display_name": "Rule Writer"}, 200, nil)
+
+    for i := 0; i < 3; i++ {
+        CreateArticle(t, access, fmt.Sprintf("Article %d", i), "content", 201)
+    }
+
+    // Страница автора листается так же, как /articles
+    var page UserPage
+    getJSON(t, apiBase+"/users/"+username+"?limit=2", 200, &page)
+    if page.Profile.Username != username || page.Profile.DisplayName != "Rule Writer" {
+        t.Fatalf("User page: unexpected profile %+v", page.Profile)
+    }
+    if len(page.Articles) != 2 || page.NextCursor == "" {
+        t.Fatalf("User page: expected 2 articles and cursor, got %d, %q", len(page.Articles), page.NextCursor)
+    }
+    if page.Articles[0].AuthorProfile.DisplayName != "Rule Writer" {
+        t.Errorf("Article author profile: unexpected %+v", page.Articles[0].AuthorProfile)
+    }
+    var last UserPage
+    getJSON(t, apiBase+"/users/"+username+"?"+url.Values{"limit": {"2"}, "cursor": {page.NextCursor}}.Encode(), 200, &last)
+    if len(last.Articles) != 1 || last.NextCursor != "" {
+        t.Errorf("User page: expected last article, got %d, %q", len(last.Articles), last.NextCursor)
+    }
+
+    getJSON(t, apiBase+"/users/"+username+"?cursor=garbage", 400, nil)
+    getJSON(t, apiBase+"/users/nobody-here", 404, nil)
+}
+
+func PutProfile(t *testing.T, access string, body interface{}, wantStatus int, out interface{}) {
+    b, _ := json.Marshal(body)
+    req, _ := http.NewRequest("PUT", apiBase+"/users/me", bytes.NewReader(b))
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("Authorization", "Bearer "+access)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("PUT /users/me failed: %v", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != wantStatus {
+        t.Fatalf("PUT /users/me: expected %d, got %d", wantStatus, resp.StatusCode)
+    }
+    if out != nil {
+        json.NewDecoder(resp.Body).Decode(out)
+    }
+}
@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestUserExport(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+    PutProfile(t, access, map[string]interface{}{"bio": "Export me"}, 200, nil)
+
+    upload := uploadTempMedia(t, access)
+    resp, err := postUpload(upload, []byte("media body"))
+    if err != nil || resp.StatusCode != 204 {
+        t.Fatalf("Media upload failed: %v", err)
+    }
+    resp.Body.Close()
+    fileKey := upload.FileID
+    articleID := CreateArticleWithMedia(t, access, "Exported: rule", "Body of the rule", []string{fileKey}, 201)
+    trashedID := CreateArticle(t, access, "Trashed rule", "Gone", 201)
+    SendArticleAction(t, access, "DELETE", trashedID, "", 200)
+
+    files := ExportUserData(t, access)
+    var profile Profile
+    if err := json.Unmarshal(files["profile.json"], &profile); err != nil || profile.Bio != "Export me" {
+        t.Errorf("profile.json: unexpected %s", files["profile.json"])
+    }
+
+    // Статья - markdown с front matter, медиа лежат рядом
+    article := string(files["articles/"+articleID+".md"])
+    if !strings.HasPrefix(article, "---\nid: "+articleID+"\ntitle: \"Exported: rule\"\n") || !strings.HasSuffix(article, "---\n\nBody of the rule\n") {
+        t.Errorf("Article markdown: unexpected %q", article)
+    }
+    mediaFound := false
+    for name, body := range files {
+        if strings.HasPrefix(name, "media/"+fileKey+"/") && string(body) == "media body" {
+            mediaFound = true
+        }
+    }
+    if !mediaFound {
+        t.Errorf("Media file missing from export")
+    }
+
+    // Статьи из корзины тоже выгружаются
+    if !strings.Contains(string(files["articles/"+trashedID+".md"]), "deleted_at: ") {
+        t.Errorf("Trashed article missing from export")
+    }
+}
+
+func TestUserDeleteReassign(t *testing.T) {
+    ResetDB(t)
+    adminName, adminPassword := UniqueNamedUser("admin")
+    RegisterUser(t, adminName, adminPassword)
+    adminAccess, _ := LoginUser(t, adminName, adminPassword)
+    username, password := UniqueNamedUser("leaving")
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    articleID := CreateArticle(t, access, "Inherited rule", "content", 201)
+
+    // Последнего администратора удалить нельзя
+    DeleteAccount(t, adminAccess, map[string]string{"password": adminPassword, "articles": "delete"}, 409)
+
+    DeleteAccount(t, access, map[string]string{"password": "wrong", "articles": "delete"}, 403)
+    DeleteAccount(t, access, map[string]string{"password": password, "articles": "reassign"}, 400)
+    DeleteAccount(t, access, map[string]string{"password": password, "articles": "reassign", "reassign_to": "nobody_here"}, 404)
+    DeleteAccount(t, access, map[string]string{"password": password, "articles": "reassign", "reassign_to": adminName}, 200)
+
+    if author := GetArticle(t, articleID, 200).Author; author != adminName {
+        t.Errorf("Reassigned article: expected author %s, got %s", adminName, author)
+    }
+    // Ревизии удаленного пользователя сохраняют автора
+    if revisions := ListRevisions(t, articleID); len(revisions) == 0 || !strings.HasPrefix(revisions[0].Author, "deleted_") {
+        t.Errorf("Revision author of a deleted account: got %+v", revisions)
+    }
+
+    // Войти в удаленный аккаунт нельзя, сессии отозваны, публичной страницы нет
+    LoginUserExpectStatus(t, username, password, 401)
+    GetJSONAuth(t, access, "/users/me", 401, nil)
+    getJSON(t, apiBase+"/users/"+username, 404, nil)
+
+    // Имя освободилось
+    RegisterUser(t, username, password)
+    LoginUser(t, username, password)
+}
+
+func TestLastAdminsDeleteConcurrently(t *testing.T) {
+    ResetDB(t)
+    first, firstPassword := UniqueNamedUser("admin")
+    RegisterUser(t, first, firstPassword)
+    firstAccess, _ := LoginUser(t, first, firstPassword)
+    second, secondPassword := UniqueNamedUser("admin")
+    RegisterUser(t, second, secondPassword)
+    SetUserRole(t, firstAccess, second, "admin", 200)
+    secondAccess, _ := LoginUser(t, second, secondPassword)
+
+    // Два последних администратора удаляются одновременно, остаться должен один
+    statuses := make([]int, 2)
+    var wg sync.WaitGroup
+    for i, account := range [][2]string{{firstAccess, firstPassword}, {secondAccess, secondPassword}} {
+        wg.Add(1)
+        go func(i int, access, password string) {
+            defer wg.Done()
+            statuses[i] = deleteAccountStatus(t, access, map[string]string{"password": password, "articles": "delete"})
+        }(i, account[0], account[1])
+    }
+    wg.Wait()
+    if statuses[0]+statuses[1] != 200+409 {
+        t.Errorf("Concurrent deletion of the last admins: got %v, expected one 200 and one 409", statuses)
+    }
+}
+
+func TestUserDeleteArticles(t *testing.T) {
+    ResetDB(t)
+    adminName, adminPassword := UniqueNamedUser("admin")
+    RegisterUser(t, adminName, adminPassword)
+    username, password := UniqueNamedUser("leaving")
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    articleID := CreateArticle(t, access, "Removed rule", "content", 201)
+    DeleteAccount(t, access, map[string]string{"password": password, "articles": "delete"}, 200)
+
+    GetArticle(t, articleID, 404)
+}
+
+// ExportUserData скачивает архив /users/me/export и возвращает его файлы
+func ExportUserData(t *testing.T, access string) map[string][]byte {
+    req, _ := http.NewRequest("GET", apiBase+"/users/me/export", nil)
+    req.Header.Set("Authorization", "Bearer "+access)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("Export failed: %v", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "application/zip" {
+        t.Fatalf("Export: expected 200 zip, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+    }
+    body, _ := io.ReadAll(resp.Body)
+    archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+    if err != nil {
+        t.Fatalf("Export: bad zip: %v", err)
+    }
+    files := map[string][]byte{}
+    for _, f := range archive.File {
+        r, err := f.Open()
+        if err != nil {
+            t.Fatalf("Export: can not open %s: %v", f.Name, err)
+        }
+        files[f.Name], _ = io.ReadAll(r)
+        r.Close()
+    }
+    return files
+}
+
+func DeleteAccount(t *testing.T, access string, body interface{}, wantStatus int) {
+    if status := deleteAccountStatus(t, access, body); status != wantStatus {
+        t.Fatalf("DELETE /users/me: expected %d, got %d", wantStatus, status)
+    }
+}
+
+func deleteAccountStatus(t *testing.T, access string, body interface{}) int {
+    b, _ := json.Marshal(body)
+    req, _ := http.NewRequest("DELETE", apiBase+"/users/me", bytes.NewReader(b))
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("Authorization", "Bearer "+access)
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Errorf("DELETE /users/me failed: %v", err)
+        return 0
+    }
+    resp.Body.Close()
+    return resp.StatusCode
+}
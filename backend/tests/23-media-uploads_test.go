@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// Требует MEDIA_ALLOWED_TYPES с text/plain и MEDIA_MAX_UPLOAD_SIZE=1024 (см. test.docker-compose.yml)
+
+func TestUploadLimits(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    // Тип и размер проверяются еще до выдачи загрузки
+    if status, _ := requestUpload(t, access, map[string]interface{}{"file_name": "run.sh", "content_type": "application/x-sh", "size": 10}); status != 415 {
+        t.Errorf("Disallowed type: expected 415, got %d", status)
+    }
+    if status, _ := requestUpload(t, access, map[string]interface{}{"file_name": "big.png", "content_type": "image/png", "size": 4096}); status != 413 {
+        t.Errorf("Declared size over limit: expected 413, got %d", status)
+    }
+    if status, _ := requestUpload(t, access, map[string]interface{}{"content_type": "image/png", "size": 10}); status != 400 {
+        t.Errorf("Missing file name: expected 400, got %d", status)
+    }
+
+    // Политика не пускает файл больше лимита, даже если размер занижен
+    upload := uploadTempMedia(t, access)
+    resp, err := postUpload(upload, bytes.Repeat([]byte("x"), 2048))
+    if err != nil {
+        t.Fatalf("Upload failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode == 204 {
+        t.Errorf("Oversized upload accepted by storage")
+    }
+
+    // Файл больше заявленного размера, но в пределах лимита, тоже не принимается
+    upload = uploadTempMedia(t, access)
+    resp, err = postUpload(upload, bytes.Repeat([]byte("x"), 512))
+    if err != nil {
+        t.Fatalf("Upload failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode == 204 {
+        t.Errorf("Upload larger than the declared size accepted by storage")
+    }
+
+    // Тип файла закреплен в политике
+    upload = uploadTempMedia(t, access)
+    upload.FormData["Content-Type"] = "image/png"
+    resp, err = postUpload(upload, []byte("not text"))
+    if err != nil {
+        t.Fatalf("Upload failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode == 204 {
+        t.Errorf("Upload with another content type accepted by storage")
+    }
+}
+
+func TestUploadOwnership(t *testing.T) {
+    ResetDB(t)
+    owner, password := UniqueNamedUser("owner")
+    RegisterUser(t, owner, password)
+    ownerAccess, _ := LoginUser(t, owner, password)
+    thief, thiefPassword := UniqueNamedUser("thief")
+    RegisterUser(t, thief, thiefPassword)
+    thiefAccess, _ := LoginUser(t, thief, thiefPassword)
+
+    upload := uploadTempMedia(t, ownerAccess)
+    resp, err := postUpload(upload, []byte("mine"))
+    if err != nil || resp.StatusCode != 204 {
+        t.Fatalf("Upload failed: %v", err)
+    }
+    resp.Body.Close()
+
+    // Чужую загрузку нельзя прикрепить ни к статье, ни как аватар
+    CreateArticleWithMedia(t, thiefAccess, "Stolen", "content", []string{upload.FileID}, 403)
+    PutProfile(t, thiefAccess, map[string]interface{}{"avatar": upload.FileID}, 403, nil)
+    CreateArticleWithMedia(t, thiefAccess, "Guessed", "content", []string{"00000000-0000-0000-0000-000000000000"}, 403)
+
+    // Владелец прикрепляет ее, имя файла берется из запроса на загрузку
+    articleUUID := CreateArticleWithMedia(t, ownerAccess, "Mine", "content", []string{upload.FileID}, 201)
+    got := GetArticle(t, articleUUID, 200)
+    if len(got.Media) != 1 || got.Media[0].FileName != "file.txt" {
+        t.Errorf("Attached media: unexpected %+v", got.Media)
+    }
+
+    // Редактор может сохранить медиа чужой статьи, но не унести их в свою
+    SetUserRole(t, ownerAccess, thief, "editor", 200)
+    thiefAccess, _ = LoginUser(t, thief, thiefPassword)
+    b, _ := json.Marshal(map[string]interface{}{"content": "edited", "media": []string{upload.FileID}})
+    req, _ := http.NewRequest("PUT", apiBase+"/articles/"+articleUUID, bytes.NewReader(b))
+    req.Header.Set("Authorization", "Bearer "+thiefAccess)
+    req.Header.Set("Content-Type", "application/json")
+    resp, err = http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("UpdateArticle failed: %v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != 200 {
+        t.Errorf("Editor keeping media: expected 200, got %d", resp.StatusCode)
+    }
+    CreateArticleWithMedia(t, thiefAccess, "Stolen again", "content", []string{upload.FileID}, 403)
+}
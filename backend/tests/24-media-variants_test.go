@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMediaVariants(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    wide := uploadImage(t, access, 1000, 8)
+    small := uploadImage(t, access, 200, 8)
+    articleID := CreateArticleWithMedia(t, access, "Pictures", "content", []string{wide, small}, 201)
+
+    // Варианты готовятся в фоне
+    var variants, smallVariants map[string]string
+    for i := 0; i < 50 && len(variants) == 0; i++ {
+        time.Sleep(200 * time.Millisecond)
+        for _, media := range GetArticle(t, articleID, 200).Media {
+            if strings.HasSuffix(media.S3Key, wide) {
+                variants = media.Variants
+            }
+            if strings.HasSuffix(media.S3Key, small) {
+                smallVariants = media.Variants
+            }
+        }
+    }
+
+    // Только уменьшенные копии: 1600 шире оригинала
+    for _, name := range []string{"jpeg_320", "jpeg_800"} {
+        if variants[name] == "" || mediaStatus(t, variants[name]) != 200 {
+            t.Errorf("Variant %s is not available: %+v", name, variants)
+        }
+    }
+    if _, ok := variants["jpeg_1600"]; ok {
+        t.Errorf("Upscaled variant generated: %+v", variants)
+    }
+    if len(smallVariants) != 0 {
+        t.Errorf("Variants of an image narrower than 320px: %+v", smallVariants)
+    }
+}
+
+// uploadImage загружает PNG заданного размера и возвращает его ключ
+func uploadImage(t *testing.T, access string, width, height int) string {
+    img := image.NewNRGBA(image.Rect(0, 0, width, height))
+    for x := 0; x < width; x++ {
+        for y := 0; y < height; y++ {
+            img.Set(x, y, color.NRGBA{R: 200, G: 80, B: 40, A: 255})
+        }
+    }
+    var data bytes.Buffer
+    png.Encode(&data, img)
+
+    return uploadFile(t, access, "picture.png", "image/png", data.Bytes())
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"testing"
+)
+
+type MediaDetails struct {
+    S3Key    string `json:"s3_key"`
+    MimeType string `json:"mime_type"`
+    Size     int64  `json:"size"`
+    Width    int    `json:"width"`
+    Height   int    `json:"height"`
+    SHA256   string `json:"sha256"`
+}
+
+func TestMediaMetadataStripped(t *testing.T) {
+    ResetDB(t)
+    username, password := UniqueUser()
+    RegisterUser(t, username, password)
+    access, _ := LoginUser(t, username, password)
+
+    // JPEG с EXIF, в котором лежат координаты
+    var photo bytes.Buffer
+    jpeg.Encode(&photo, image.NewNRGBA(image.Rect(0, 0, 16, 12)), nil)
+    payload := []byte("Exif\x00\x00II\x2A\x00\x08\x00\x00\x00\x00\x00\x00\x00\x00\x00GPS 55.75N 37.61E")
+    exif := append([]byte{0xFF, 0xE1, 0x00, byte(len(payload) + 2)}, payload...)
+    data := append(append(append([]byte{}, photo.Bytes()[:2]...), exif...), photo.Bytes()[2:]...)
+
+    key := uploadFile(t, access, "photo.jpg", "image/jpeg", data)
+    articleID := CreateArticleWithMedia(t, access, "Photo", "content", []string{key}, 201)
+
+    var article struct {
+        Media []MediaDetails `json:"media"`
+    }
+    getJSON(t, apiBase+"/articles/"+articleID, 200, &article)
+    if len(article.Media) != 1 {
+        t.Fatalf("Expected 1 media, got %+v", article.Media)
+    }
+    media := article.Media[0]
+
+    resp, err := http.Get(media.S3Key)
+    if err != nil {
+        t.Fatalf("Failed to get media file: %v", err)
+    }
+    stored, _ := io.ReadAll(resp.Body)
+    resp.Body.Close()
+    if bytes.Contains(stored, []byte("GPS")) {
+        t.Errorf("EXIF left in the stored file")
+    }
+
+    sum := sha256.Sum256(stored)
+    if media.MimeType != "image/jpeg" || media.Size != int64(len(stored)) || media.Width != 16 || media.Height != 12 || media.SHA256 != hex.EncodeToString(sum[:]) {
+        t.Errorf("Media details: unexpected %+v (stored %d bytes)", media, len(stored))
+    }
+
+    // Файл, который только притворяется JPEG, не прикрепляется
+    broken := uploadFile(t, access, "broken.jpg", "image/jpeg", []byte("\xFF\xD8\xFF\xE1\x7F\x00not a jpeg"))
+    CreateArticleWithMedia(t, access, "Broken", "content", []string{broken}, 422)
+}
+
+// uploadFile загружает data с заданным типом и возвращает ключ файла
+func uploadFile(t *testing.T, access, fileName, contentType string, data []byte) string {
+    status, upload := requestUpload(t, access, map[string]interface{}{
+        "file_name": fileName, "content_type": contentType, "size": len(data),
+    })
+    if status != 200 {
+        t.Fatalf("Upload-temp request failed with status: %d", status)
+    }
+    resp, err := postUpload(upload, data)
+    if err != nil || resp.StatusCode != 204 {
+        t.Fatalf("Upload failed: %v", err)
+    }
+    resp.Body.Close()
+    return upload.FileID
+}
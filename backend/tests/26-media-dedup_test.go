@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMediaDeduplication(t *testing.T) {
+    ResetDB(t)
+    first, password := UniqueNamedUser("first")
+    RegisterUser(t, first, password)
+    firstAccess, _ := LoginUser(t, first, password)
+    second, secondPassword := UniqueNamedUser("second")
+    RegisterUser(t, second, secondPassword)
+    secondAccess, _ := LoginUser(t, second, secondPassword)
+
+    logo := []byte("the same logo everywhere")
+    firstKey := uploadFile(t, firstAccess, "logo.txt", "text/plain", logo)
+    firstArticle := CreateArticleWithMedia(t, firstAccess, "First", "content", []string{firstKey}, 201)
+    firstURL := GetArticle(t, firstArticle, 200).Media[0].S3Key
+
+    // Пока политика загрузки первого файла действует (S3_PRESIGNED_LIFETIME=5),
+    // его можно перезаписать, и он не используется совместно
+    earlyKey := uploadFile(t, secondAccess, "logo.txt", "text/plain", logo)
+    earlyArticle := CreateArticleWithMedia(t, secondAccess, "Early", "content", []string{earlyKey}, 201)
+    if earlyURL := GetArticle(t, earlyArticle, 200).Media[0].S3Key; earlyURL == firstURL {
+        t.Errorf("Object shared while its upload policy is valid")
+    }
+    time.Sleep(6 * time.Second)
+
+    secondKey := uploadFile(t, secondAccess, "logo.txt", "text/plain", logo)
+    secondArticle := CreateArticleWithMedia(t, secondAccess, "Second", "content", []string{secondKey}, 201)
+
+    // Вторая статья ссылается на уже сохраненный файл, дубликат удален
+    secondURL := GetArticle(t, secondArticle, 200).Media[0].S3Key
+    if secondURL != firstURL {
+        t.Fatalf("Duplicate not shared: %s vs %s", secondURL, firstURL)
+    }
+    if status := mediaStatus(t, strings.TrimSuffix(firstURL, firstKey)+secondKey); status != 404 {
+        t.Errorf("Duplicate upload kept, status %d", status)
+    }
+
+    // Общий файл удаляется вместе с последней статьей
+    SendArticleAction(t, firstAccess, "DELETE", firstArticle, "", 200)
+    SendArticleAction(t, firstAccess, "DELETE", firstArticle, "/purge", 200)
+    if status := mediaStatus(t, firstURL); status != 200 {
+        t.Errorf("Shared object deleted with the first article, status %d", status)
+    }
+    SendArticleAction(t, secondAccess, "DELETE", secondArticle, "", 200)
+    SendArticleAction(t, secondAccess, "DELETE", secondArticle, "/purge", 200)
+    if status := mediaStatus(t, firstURL); status != 404 {
+        t.Errorf("Object left after the last article was purged, status %d", status)
+    }
+}
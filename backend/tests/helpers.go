@@ -81,4 +81,30 @@ func LoginUser(t *testing.T, username, password string) (string, string) {
 
 func UniqueUser() (string, string) {
     return fmt.Sprintf("user_%d", time.Now().Unix()), "password123"
-}
\ No newline at end of file
+}
+// UniqueNamedUser returns a username with the given prefix that does not
+// collide with other users created within the same second
+func UniqueNamedUser(prefix string) (string, string) {
+    return fmt.Sprintf("%s_%d", prefix, time.Now().UnixNano()%1e9), "password123"
+}
+
+func CreateArticleWithMedia(t *testing.T, access, title, content string, media []string, wantStatus int) string {
+    body := map[string]interface{}{"title": title, "content": content, "media": media}
+    b, _ := json.Marshal(body)
+    req, _ := http.NewRequest("POST", apiBase+"/articles/", bytes.NewReader(b))
+    req.Header.Set("Authorization", "Bearer "+access)
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("CreateArticle failed: %v", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != wantStatus {
+        t.Fatalf("CreateArticle: expected %d, got %d", wantStatus, resp.StatusCode)
+    }
+    var out struct {
+        ID string `json:"id"`
+    }
+    json.NewDecoder(resp.Body).Decode(&out)
+    return out.ID
+}
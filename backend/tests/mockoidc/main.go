@@ -0,0 +1,202 @@
+// mockoidc - минимальный OIDC провайдер для интеграционных тестов.
+// Пользователь не вводит пароль: subject и claims передаются параметрами
+// sub, email и preferred_username запроса /authorize, deny=1 имитирует отказ.
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type authCode struct {
+    clientID      string
+    redirectURI   string
+    nonce         string
+    codeChallenge string
+    claims        map[string]string
+}
+
+var (
+    issuer       = getenv("MOCK_OIDC_ISSUER", "http://localhost:9400")
+    clientID     = getenv("MOCK_OIDC_CLIENT_ID", "rulehub")
+    clientSecret = getenv("MOCK_OIDC_CLIENT_SECRET", "secret")
+
+    signingKey *rsa.PrivateKey
+
+    mu    sync.Mutex
+    codes = map[string]authCode{}
+)
+
+func getenv(name, fallback string) string {
+    if v := os.Getenv(name); v != "" {
+        return v
+    }
+    return fallback
+}
+
+func main() {
+    var err error
+    signingKey, err = rsa.GenerateKey(rand.Reader, 2048)
+    if err != nil {
+        log.Fatalf("failed to generate key: %v", err)
+    }
+
+    http.HandleFunc("/.well-known/openid-configuration", discovery)
+    http.HandleFunc("/jwks", jwks)
+    http.HandleFunc("/authorize", authorize)
+    http.HandleFunc("/token", token)
+
+    addr := getenv("MOCK_OIDC_ADDR", ":9400")
+    log.Printf("Mock OIDC issuer %s listening on %s", issuer, addr)
+    log.Fatal(http.ListenAndServe(addr, nil))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(v)
+}
+
+func discovery(w http.ResponseWriter, r *http.Request) {
+    writeJSON(w, 200, map[string]interface{}{
+        "issuer":                                issuer,
+        "authorization_endpoint":                issuer + "/authorize",
+        "token_endpoint":                        issuer + "/token",
+        "jwks_uri":                              issuer + "/jwks",
+        "response_types_supported":              []string{"code"},
+        "subject_types_supported":               []string{"public"},
+        "id_token_signing_alg_values_supported": []string{"RS256"},
+        "code_challenge_methods_supported":      []string{"S256"},
+    })
+}
+
+func jwks(w http.ResponseWriter, r *http.Request) {
+    pub := signingKey.PublicKey
+    writeJSON(w, 200, map[string]interface{}{
+        "keys": []map[string]string{{
+            "kty": "RSA",
+            "kid": "mock",
+            "use": "sig",
+            "alg": "RS256",
+            "n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+            "e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+        }},
+    })
+}
+
+func authorize(w http.ResponseWriter, r *http.Request) {
+    q := r.URL.Query()
+    redirectURI, err := url.Parse(q.Get("redirect_uri"))
+    if err != nil || q.Get("client_id") != clientID || q.Get("response_type") != "code" {
+        http.Error(w, "bad authorization request", http.StatusBadRequest)
+        return
+    }
+    // Провайдер обязан требовать PKCE от клиента
+    if q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+        http.Error(w, "PKCE required", http.StatusBadRequest)
+        return
+    }
+
+    params := url.Values{"state": {q.Get("state")}}
+    if q.Get("deny") == "1" {
+        params.Set("error", "access_denied")
+    } else {
+        code := randomString()
+        mu.Lock()
+        codes[code] = authCode{
+            clientID:      clientID,
+            redirectURI:   q.Get("redirect_uri"),
+            nonce:         q.Get("nonce"),
+            codeChallenge: q.Get("code_challenge"),
+            claims: map[string]string{
+                "sub":                q.Get("sub"),
+                "email":              q.Get("email"),
+                "preferred_username": q.Get("preferred_username"),
+            },
+        }
+        mu.Unlock()
+        params.Set("code", code)
+    }
+    redirectURI.RawQuery = params.Encode()
+    http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func token(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodPost {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    id, secret, ok := r.BasicAuth()
+    if !ok || id != clientID || secret != clientSecret {
+        writeJSON(w, 401, map[string]string{"error": "invalid_client"})
+        return
+    }
+    r.ParseForm()
+
+    // Код одноразовый
+    mu.Lock()
+    code, found := codes[r.PostForm.Get("code")]
+    delete(codes, r.PostForm.Get("code"))
+    mu.Unlock()
+
+    verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+    switch {
+    case r.PostForm.Get("grant_type") != "authorization_code", !found:
+        writeJSON(w, 400, map[string]string{"error": "invalid_grant"})
+        return
+    case r.PostForm.Get("redirect_uri") != code.redirectURI:
+        writeJSON(w, 400, map[string]string{"error": "invalid_grant", "error_description": "redirect_uri mismatch"})
+        return
+    case base64.RawURLEncoding.EncodeToString(verifier[:]) != code.codeChallenge:
+        writeJSON(w, 400, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+        return
+    }
+
+    claims := jwt.MapClaims{
+        "iss":   issuer,
+        "aud":   code.clientID,
+        "exp":   time.Now().Add(5 * time.Minute).Unix(),
+        "iat":   time.Now().Unix(),
+        "nonce": code.nonce,
+    }
+    for name, value := range code.claims {
+        if value != "" {
+            claims[name] = value
+        }
+    }
+    if claims["email"] != nil {
+        claims["email_verified"] = true
+    }
+    idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+    idToken.Header["kid"] = "mock"
+    signed, err := idToken.SignedString(signingKey)
+    if err != nil {
+        writeJSON(w, 500, map[string]string{"error": "server_error"})
+        return
+    }
+
+    writeJSON(w, 200, map[string]interface{}{
+        "access_token": randomString(),
+        "token_type":   "Bearer",
+        "expires_in":   300,
+        "id_token":     signed,
+    })
+}
+
+func randomString() string {
+    buf := make([]byte, 24)
+    rand.Read(buf)
+    return base64.RawURLEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// Cursor is an opaque keyset pagination position: the value of the sort
+// column and the ID of the last returned row, and the ordering it belongs to.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// EncodeCursor serializes a cursor into a URL-safe token
+func EncodeCursor(cursor Cursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor parses a token produced by EncodeCursor
+func DecodeCursor(token string) (Cursor, error) {
+	var cursor Cursor
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor, fmt.Errorf("error decoding cursor: %w", err)
+	}
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return cursor, fmt.Errorf("error parsing cursor: %w", err)
+	}
+	if cursor.ID == "" {
+		return cursor, fmt.Errorf("cursor has no id")
+	}
+	return cursor, nil
+}
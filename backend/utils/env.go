@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetDurationEnv reads a duration given in seconds from an environment variable
+func GetDurationEnv(name string, fallback time.Duration) time.Duration {
+	secStr := os.Getenv(name)
+	if secStr == "" {
+		return fallback
+	}
+	sec, err := strconv.Atoi(secStr)
+	if err != nil || sec <= 0 {
+		return fallback
+	}
+	return time.Duration(sec) * time.Second
+}
+
+// GetIntEnv reads a positive integer from an environment variable
+func GetIntEnv(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// MediaUploadLimits returns the largest accepted upload in bytes
+// (MEDIA_MAX_UPLOAD_SIZE) and the accepted MIME types (MEDIA_ALLOWED_TYPES,
+// comma separated)
+func MediaUploadLimits() (int64, []string) {
+	maxSize := int64(GetIntEnv("MEDIA_MAX_UPLOAD_SIZE", 10<<20))
+
+	allowed := []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+	if env := os.Getenv("MEDIA_ALLOWED_TYPES"); env != "" {
+		allowed = nil
+		for _, mime := range strings.Split(env, ",") {
+			if mime = strings.TrimSpace(strings.ToLower(mime)); mime != "" {
+				allowed = append(allowed, mime)
+			}
+		}
+	}
+	return maxSize, allowed
+}
+
+func GetPresignedLifetime() time.Duration {
+	secStr := os.Getenv("S3_PRESIGNED_LIFETIME")
+	if secStr == "" {
+		return time.Hour // default 1 hour
+	}
+	sec, err := strconv.Atoi(secStr)
+	if err != nil || sec <= 0 {
+		return time.Hour
+	}
+	return time.Duration(sec) * time.Second
+}
@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// ApplyOrientation turns img upright according to an EXIF orientation: 2-4
+// mirror or rotate it by 180 degrees, 5-8 also swap width and height
+func ApplyOrientation(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	bounds := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	w, h := bounds.Dx(), bounds.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			// Source pixel shown at x, y
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):][:4], src.Pix[src.PixOffset(sx, sy):][:4])
+		}
+	}
+	return dst
+}
+
+// ResizeToWidth scales img down to width pixels, keeping the aspect ratio
+func ResizeToWidth(img image.Image, width int) *image.NRGBA {
+	bounds := img.Bounds()
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// EncodeJPEG encodes img as JPEG. Transparent pixels are put on white, as
+// JPEG has no alpha channel.
+func EncodeJPEG(img image.Image, quality int) ([]byte, error) {
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WebPEncoder returns the path of the cwebp binary used to encode WebP
+// (MEDIA_CWEBP, cwebp from PATH by default), or "" when there is none. The
+// Go standard library and x/image can only decode WebP.
+func WebPEncoder() string {
+	name := os.Getenv("MEDIA_CWEBP")
+	if name == "" {
+		name = "cwebp"
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// EncodeWebP encodes img as WebP with the cwebp binary at encoder
+func EncodeWebP(encoder string, img image.Image, quality int) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "rulehub-webp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	// Older cwebp builds can not read stdin, so files are used both ways
+	src, dst := filepath.Join(dir, "in.png"), filepath.Join(dir, "out.webp")
+	f, err := os.Create(src)
+	if err != nil {
+		return nil, err
+	}
+	err = (&png.Encoder{CompressionLevel: png.NoCompression}).Encode(f, img)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := exec.Command(encoder, "-quiet", "-q", strconv.Itoa(quality), src, "-o", dst).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("cwebp: %w: %s", err, bytes.TrimSpace(out))
+	}
+	return os.ReadFile(dst)
+}
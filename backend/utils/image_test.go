@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+func TestJPEGOrientation(t *testing.T) {
+	clean := testImage(t, func(w *bytes.Buffer, img image.Image) error { return jpeg.Encode(w, img, nil) })
+	rotated := append(append(append([]byte{}, clean[:2]...), exifSegment(6)...), clean[2:]...)
+
+	if got := JPEGOrientation(rotated); got != 6 {
+		t.Errorf("rotated photo: got %d", got)
+	}
+	if got := JPEGOrientation(clean); got != 1 {
+		t.Errorf("photo without EXIF: got %d", got)
+	}
+	if got := JPEGOrientation([]byte("not an image")); got != 1 {
+		t.Errorf("non-image: got %d", got)
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// 3x2 image with a distinct value in every pixel:
+	//   0 1 2
+	//   3 4 5
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for i := 0; i < 6; i++ {
+		src.SetNRGBA(i%3, i/3, color.NRGBA{R: uint8(i), A: 255})
+	}
+
+	tests := []struct {
+		orientation int
+		want        [][]uint8
+	}{
+		{1, [][]uint8{{0, 1, 2}, {3, 4, 5}}},
+		{2, [][]uint8{{2, 1, 0}, {5, 4, 3}}},
+		{3, [][]uint8{{5, 4, 3}, {2, 1, 0}}},
+		{4, [][]uint8{{3, 4, 5}, {0, 1, 2}}},
+		{5, [][]uint8{{0, 3}, {1, 4}, {2, 5}}},
+		{6, [][]uint8{{3, 0}, {4, 1}, {5, 2}}},
+		{7, [][]uint8{{5, 2}, {4, 1}, {3, 0}}},
+		{8, [][]uint8{{2, 5}, {1, 4}, {0, 3}}},
+	}
+	for _, tt := range tests {
+		got := ApplyOrientation(src, tt.orientation)
+		if got.Bounds().Dx() != len(tt.want[0]) || got.Bounds().Dy() != len(tt.want) {
+			t.Errorf("orientation %d: got size %v", tt.orientation, got.Bounds())
+			continue
+		}
+		for y, row := range tt.want {
+			for x, want := range row {
+				if r, _, _, _ := got.At(x, y).RGBA(); uint8(r>>8) != want {
+					t.Errorf("orientation %d: pixel %d,%d is %d, want %d", tt.orientation, x, y, r>>8, want)
+				}
+			}
+		}
+	}
+}
@@ -2,40 +2,127 @@ package utils
 
 import (
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateAccessToken(userID string, username string) (string, error) {
+// GenerateAccessToken signs a short-lived token for the given session (sid)
+// with the current key of the access keyring
+func GenerateAccessToken(userID string, username string, role string, sessionID string) (string, error) {
+    if AccessKeyring() == nil {
+        return "", fmt.Errorf("access keyring is not initialised")
+    }
     claims := jwt.MapClaims{
         "user_id": userID,
         "username": username,
+        "role":    role,
+        "sid":     sessionID,
         "exp":     time.Now().Add(15 * time.Minute).Unix(), // Access token expires in 15 minutes
         "iat":     time.Now().Unix(),
     }
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-    return token.SignedString([]byte(os.Getenv("PASSWORD_JWT_ACCESS_SECRET")))
+    return AccessKeyring().Sign(claims)
 }
 
-func GenerateRefreshToken(userID string) (string, error) {
+// RefreshTokenLifetime is how long a refresh token and its session stay valid
+// without being used
+const RefreshTokenLifetime = 7 * 24 * time.Hour
+
+func GenerateRefreshToken(userID string, jti string, expiresAt time.Time) (string, error) {
     claims := jwt.MapClaims{
         "user_id": userID,
-        "exp":     time.Now().Add(7 * 24 * time.Hour).Unix(), // Refresh token expires in 7 days
+        "jti":     jti,
+        "exp":     expiresAt.Unix(),
         "iat":     time.Now().Unix(),
     }
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-    return token.SignedString([]byte(os.Getenv("PASSWORD_JWT_REFRESH_SECRET")))
+    return RefreshKeyring().Sign(claims)
 }
 
-func ValidateToken(tokenString string, secret []byte) (jwt.MapClaims, error) {
-    token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-        if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-            return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-        }
-        return secret, nil
-    })
+// TwoFactorChallengeLifetime is how long a user has to enter the second
+// factor after the password was accepted
+const TwoFactorChallengeLifetime = 5 * time.Minute
+
+// GenerateChallengeToken signs the token that proves the password step of a
+// two-step login. It is only ever read by this service, like refresh tokens.
+func GenerateChallengeToken(userID string) (string, error) {
+    claims := jwt.MapClaims{
+        "user_id": userID,
+        "typ":     "2fa",
+        "exp":     time.Now().Add(TwoFactorChallengeLifetime).Unix(),
+        "iat":     time.Now().Unix(),
+    }
+    return RefreshKeyring().Sign(claims)
+}
+
+// ValidateChallengeToken returns the user of a valid challenge token
+func ValidateChallengeToken(tokenString string) (string, error) {
+    claims, err := ValidateToken(tokenString, RefreshKeyring())
+    if err != nil {
+        return "", err
+    }
+    userID, ok := claims["user_id"].(string)
+    if typ, _ := claims["typ"].(string); !ok || typ != "2fa" {
+        return "", fmt.Errorf("not a challenge token")
+    }
+    return userID, nil
+}
+
+// OIDCStateLifetime is how long a user has to sign in at the OIDC provider
+const OIDCStateLifetime = 10 * time.Minute
+
+// OIDCState is what the OIDC callback needs to finish the login it belongs to.
+// LinkUserID is set when a signed in user links a new identity.
+type OIDCState struct {
+    State      string
+    Nonce      string
+    Verifier   string
+    LinkUserID string
+}
+
+// GenerateOIDCStateToken signs the state kept in a cookie while the user is
+// at the OIDC provider
+func GenerateOIDCStateToken(state OIDCState) (string, error) {
+    claims := jwt.MapClaims{
+        "typ":      "oidc",
+        "state":    state.State,
+        "nonce":    state.Nonce,
+        "verifier": state.Verifier,
+        "exp":      time.Now().Add(OIDCStateLifetime).Unix(),
+        "iat":      time.Now().Unix(),
+    }
+    if state.LinkUserID != "" {
+        claims["link_user_id"] = state.LinkUserID
+    }
+    return RefreshKeyring().Sign(claims)
+}
+
+// ValidateOIDCStateToken returns the state of a valid OIDC state token
+func ValidateOIDCStateToken(tokenString string) (*OIDCState, error) {
+    claims, err := ValidateToken(tokenString, RefreshKeyring())
+    if err != nil {
+        return nil, err
+    }
+    if typ, _ := claims["typ"].(string); typ != "oidc" {
+        return nil, fmt.Errorf("not an OIDC state token")
+    }
+    state := &OIDCState{}
+    state.State, _ = claims["state"].(string)
+    state.Nonce, _ = claims["nonce"].(string)
+    state.Verifier, _ = claims["verifier"].(string)
+    state.LinkUserID, _ = claims["link_user_id"].(string)
+    if state.State == "" || state.Nonce == "" || state.Verifier == "" {
+        return nil, fmt.Errorf("incomplete OIDC state token")
+    }
+    return state, nil
+}
+
+// ValidateToken parses a token and verifies it with the keyring key named by
+// its kid header
+func ValidateToken(tokenString string, keyring *Keyring) (jwt.MapClaims, error) {
+    if keyring == nil {
+        return nil, fmt.Errorf("keyring is not initialised")
+    }
+    token, err := jwt.Parse(tokenString, keyring.Keyfunc)
 
     if err != nil {
         return nil, err
@@ -46,4 +133,4 @@ func ValidateToken(tokenString string, secret []byte) (jwt.MapClaims, error) {
     }
 
     return nil, fmt.Errorf("invalid token")
-}
\ No newline at end of file
+}
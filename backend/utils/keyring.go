@@ -0,0 +1,297 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTKey is one key of a Keyring. For asymmetric keys Private is nil when the
+// key is only kept to verify tokens signed before a rotation.
+type JWTKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private interface{}
+	Public  interface{}
+}
+
+// Keyring signs tokens with its current key and verifies them with any of its
+// keys, picked by the kid header
+type Keyring struct {
+	signing *JWTKey
+	keys    map[string]*JWTKey
+}
+
+// NewKeyring builds a keyring that signs with the first key. Every key is
+// accepted for verification.
+func NewKeyring(signing *JWTKey, verify ...*JWTKey) *Keyring {
+	k := &Keyring{signing: signing, keys: map[string]*JWTKey{signing.ID: signing}}
+	for _, key := range verify {
+		if _, ok := k.keys[key.ID]; !ok {
+			k.keys[key.ID] = key
+		}
+	}
+	return k
+}
+
+// NewHMACKeyring is a keyring with a single HS256 secret and no kid
+func NewHMACKeyring(secret []byte) *Keyring {
+	return NewKeyring(&JWTKey{Method: jwt.SigningMethodHS256, Private: secret, Public: secret})
+}
+
+// Sign signs the claims with the current key and sets the kid header
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signing.Method, claims)
+	if k.signing.ID != "" {
+		token.Header["kid"] = k.signing.ID
+	}
+	return token.SignedString(k.signing.Private)
+}
+
+// Keyfunc resolves the verification key of a token by its kid. The token
+// algorithm must be the one of the key, so an RSA public key can never be
+// used as an HMAC secret.
+func (k *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.Public, nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the keyring. Symmetric keys are never
+// published.
+func (k *Keyring) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range k.sortedKeys() {
+		jwk, ok := publicJWK(key.Public)
+		if !ok {
+			continue
+		}
+		jwk.Kid = key.ID
+		jwk.Use = "sig"
+		jwk.Alg = key.Method.Alg()
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// sortedKeys lists the signing key first, then the others by kid
+func (k *Keyring) sortedKeys() []*JWTKey {
+	keys := []*JWTKey{k.signing}
+	var rest []string
+	for kid := range k.keys {
+		if kid != k.signing.ID {
+			rest = append(rest, kid)
+		}
+	}
+	sort.Strings(rest)
+	for _, kid := range rest {
+		keys = append(keys, k.keys[kid])
+	}
+	return keys
+}
+
+func publicJWK(public interface{}) (JWK, bool) {
+	switch pub := public.(type) {
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(pub)}, true
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	}
+	return JWK{}, false
+}
+
+// jwkThumbprint computes the RFC 7638 thumbprint of a public key, used as kid
+func jwkThumbprint(public interface{}) (string, error) {
+	jwk, ok := publicJWK(public)
+	if !ok {
+		return "", fmt.Errorf("unsupported public key type %T", public)
+	}
+	var canonical string
+	switch jwk.Kty {
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":"%s","kty":"OKP","x":"%s"}`, jwk.Crv, jwk.X)
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwk.E, jwk.N)
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// newAsymmetricKey wraps an Ed25519 or RSA key, private or public, into a
+// JWTKey whose kid is the key thumbprint
+func newAsymmetricKey(key interface{}) (*JWTKey, error) {
+	jwtKey := &JWTKey{}
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		jwtKey.Method, jwtKey.Private, jwtKey.Public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		jwtKey.Method, jwtKey.Public = jwt.SigningMethodEdDSA, k
+	case *rsa.PrivateKey:
+		jwtKey.Method, jwtKey.Private, jwtKey.Public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		jwtKey.Method, jwtKey.Public = jwt.SigningMethodRS256, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, Ed25519 or RSA expected", key)
+	}
+	if pub, ok := jwtKey.Public.(*rsa.PublicKey); ok && pub.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("RSA key is %d bits, at least 2048 required", pub.N.BitLen())
+	}
+
+	kid, err := jwkThumbprint(jwtKey.Public)
+	if err != nil {
+		return nil, err
+	}
+	jwtKey.ID = kid
+	return jwtKey, nil
+}
+
+// ParseJWTKeyPEM reads an Ed25519 or RSA key from PEM. Private keys may be
+// PKCS#8 or PKCS#1, public keys PKIX.
+func ParseJWTKeyPEM(data []byte) (*JWTKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newAsymmetricKey(key)
+}
+
+func loadJWTKeyFile(path string) (*JWTKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParseJWTKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// LoadKeyringFromEnv builds the access token keyring. JWT_SIGNING_KEY is the
+// PEM file of the current private key, JWT_VERIFY_KEYS a comma separated list
+// of PEM files with keys that are still accepted: the previous key after a
+// rotation, or the next one published ahead of it. Without JWT_SIGNING_KEY an
+// ephemeral Ed25519 key is generated, so tokens do not survive a restart; in
+// production (RUNTIME_PRODUCTION=true) the key is required.
+func LoadKeyringFromEnv() (*Keyring, error) {
+	var signing *JWTKey
+	if path := os.Getenv("JWT_SIGNING_KEY"); path != "" {
+		key, err := loadJWTKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if key.Private == nil {
+			return nil, fmt.Errorf("%s: signing key must be a private key", path)
+		}
+		signing = key
+	} else {
+		if os.Getenv("RUNTIME_PRODUCTION") == "true" {
+			return nil, fmt.Errorf("JWT_SIGNING_KEY must be set in production")
+		}
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signing, err = newAsymmetricKey(private)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("JWT_SIGNING_KEY is not set, signing access tokens with ephemeral key %s", signing.ID)
+	}
+
+	var verify []*JWTKey
+	for _, path := range strings.Split(os.Getenv("JWT_VERIFY_KEYS"), ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		key, err := loadJWTKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		// Only the public half is needed to verify
+		key.Private = nil
+		verify = append(verify, key)
+	}
+
+	return NewKeyring(signing, verify...), nil
+}
+
+// accessKeyring signs and verifies access tokens, see InitAccessKeyring
+var accessKeyring *Keyring
+
+// InitAccessKeyring loads the access token keys, main calls it on startup
+func InitAccessKeyring() error {
+	keyring, err := LoadKeyringFromEnv()
+	if err != nil {
+		return err
+	}
+	accessKeyring = keyring
+	return nil
+}
+
+// AccessKeyring returns the keyring used for access tokens
+func AccessKeyring() *Keyring {
+	return accessKeyring
+}
+
+// RefreshKeyring returns the keyring used for refresh tokens. They are only
+// ever read by this service, so they stay signed with a shared secret.
+func RefreshKeyring() *Keyring {
+	return NewHMACKeyring([]byte(os.Getenv("PASSWORD_JWT_REFRESH_SECRET")))
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// LoginThrottle tracks failed logins per username and per IP. Once a key has
+// used its free attempts, every further failure locks it for twice as long as
+// the previous one, up to MaxLockout. State is kept in memory, so each backend
+// instance counts on its own.
+type LoginThrottle struct {
+	UserFreeAttempts int
+	IPFreeAttempts   int
+	BaseLockout      time.Duration
+	MaxLockout       time.Duration
+	// Forget is how long after the last failure a key starts from scratch
+	Forget time.Duration
+
+	mu          sync.Mutex
+	entries     map[string]*loginAttempts
+	lastCleanup time.Time
+}
+
+type loginAttempts struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// NewLoginThrottle reads LOGIN_MAX_ATTEMPTS, LOGIN_IP_MAX_ATTEMPTS (free
+// failures) and LOGIN_LOCKOUT_MAX (seconds) from the environment
+func NewLoginThrottle() *LoginThrottle {
+	return &LoginThrottle{
+		UserFreeAttempts: GetIntEnv("LOGIN_MAX_ATTEMPTS", 5),
+		IPFreeAttempts:   GetIntEnv("LOGIN_IP_MAX_ATTEMPTS", 50),
+		BaseLockout:      time.Second,
+		MaxLockout:       GetDurationEnv("LOGIN_LOCKOUT_MAX", 15*time.Minute),
+		Forget:           time.Hour,
+		entries:          map[string]*loginAttempts{},
+	}
+}
+
+func userKey(username string) string { return "user:" + username }
+func ipKey(ip string) string         { return "ip:" + ip }
+
+// Check returns how long the username or IP is still locked, 0 if the
+// attempt may proceed
+func (t *LoginThrottle) Check(username, ip string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range []string{userKey(username), ipKey(ip)} {
+		if entry, ok := t.entries[key]; ok && entry.lockedUntil.After(now) {
+			wait = max(wait, entry.lockedUntil.Sub(now))
+		}
+	}
+	return wait
+}
+
+// Failure records a failed attempt and returns the resulting lockout, 0 while
+// free attempts remain
+func (t *LoginThrottle) Failure(username, ip string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.cleanup(now)
+
+	lockout := t.fail(userKey(username), t.UserFreeAttempts, now)
+	return max(lockout, t.fail(ipKey(ip), t.IPFreeAttempts, now))
+}
+
+// Success clears the failures of the username. The IP keeps its count, so
+// one valid account can not be used to reset guessing on others.
+func (t *LoginThrottle) Success(username string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.entries, userKey(username))
+}
+
+// Reset forgets every attempt
+func (t *LoginThrottle) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries = map[string]*loginAttempts{}
+}
+
+func (t *LoginThrottle) fail(key string, free int, now time.Time) time.Duration {
+	entry, ok := t.entries[key]
+	if !ok || now.Sub(entry.lastFailure) > t.Forget {
+		entry = &loginAttempts{}
+		t.entries[key] = entry
+	}
+	entry.failures++
+	entry.lastFailure = now
+
+	over := entry.failures - free
+	if over <= 0 {
+		return 0
+	}
+	lockout := t.MaxLockout
+	if over < 32 {
+		lockout = min(t.BaseLockout<<(over-1), t.MaxLockout)
+	}
+	entry.lockedUntil = now.Add(lockout)
+	return lockout
+}
+
+// cleanup drops keys that have been quiet for longer than Forget. It runs
+// at most once a minute, so a flood of failures does not rescan the map
+// every time.
+func (t *LoginThrottle) cleanup(now time.Time) {
+	if now.Sub(t.lastCleanup) < time.Minute {
+		return
+	}
+	t.lastCleanup = now
+	for key, entry := range t.entries {
+		if now.Sub(entry.lastFailure) > t.Forget && now.After(entry.lockedUntil) {
+			delete(t.entries, key)
+		}
+	}
+}
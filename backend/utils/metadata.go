@@ -0,0 +1,278 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"image"
+)
+
+// ErrMalformedImage is returned when a file looks like an image but its
+// structure can not be parsed
+var ErrMalformedImage = errors.New("malformed image")
+
+// MediaInfo describes the content of a stored media object. Width and Height
+// are zero for files that are not images.
+type MediaInfo struct {
+	MimeType string
+	Size     int64
+	Width    int
+	Height   int
+	SHA256   string
+}
+
+// InspectMedia describes data stored with the given content type
+func InspectMedia(contentType string, data []byte) MediaInfo {
+	sum := sha256.Sum256(data)
+	info := MediaInfo{
+		MimeType: contentType,
+		Size:     int64(len(data)),
+		SHA256:   hex.EncodeToString(sum[:]),
+	}
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		info.Width, info.Height = config.Width, config.Height
+	}
+	return info
+}
+
+// StripImageMetadata removes EXIF, XMP and textual metadata from JPEG, PNG
+// and WebP files without re-encoding the pixels. Other files are returned
+// as is. The EXIF orientation of JPEG photos is kept, as without it they
+// would show up rotated.
+func StripImageMetadata(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return stripJPEG(data)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return stripPNG(data)
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return stripWebP(data)
+	}
+	return data, nil
+}
+
+func stripJPEG(data []byte) ([]byte, error) {
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+	orientation := 0
+	pos := 2
+	for {
+		if pos >= len(data) || data[pos] != 0xFF {
+			return nil, ErrMalformedImage
+		}
+		// Any number of fill bytes may precede a marker
+		start := pos
+		for pos < len(data) && data[pos] == 0xFF {
+			pos++
+		}
+		if pos >= len(data) {
+			return nil, ErrMalformedImage
+		}
+		marker := data[pos]
+		pos++
+
+		// Phones append more images after the end of the primary one (MPF,
+		// gain maps, depth maps), each with its own EXIF, so nothing after
+		// the end of image is kept
+		if marker == 0xD9 {
+			out.Write(data[start:pos])
+			break
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			out.Write(data[start:pos])
+			continue
+		}
+		if pos+2 > len(data) {
+			return nil, ErrMalformedImage
+		}
+		end := pos + int(binary.BigEndian.Uint16(data[pos:]))
+		if end > len(data) || end < pos+2 {
+			return nil, ErrMalformedImage
+		}
+
+		switch marker {
+		case 0xE1: // APP1: EXIF or XMP
+			if payload := data[pos+2 : end]; bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+				orientation = exifOrientation(payload[6:])
+			}
+		case 0xE2: // APP2: ICC profiles are kept, the MPF index of appended images is not
+			if !bytes.HasPrefix(data[pos+2:end], []byte("MPF\x00")) {
+				out.Write(data[start:end])
+			}
+		case 0xED, 0xFE: // APP13 (IPTC) and comments
+		case 0xDA: // Start of scan, followed by entropy coded data
+			scan := jpegScanEnd(data, end)
+			out.Write(data[start:scan])
+			end = scan
+		default:
+			out.Write(data[start:end])
+		}
+		pos = end
+		// A file cut short in the scan data still decodes, keep it as is
+		if pos == len(data) && marker == 0xDA {
+			break
+		}
+	}
+
+	if orientation <= 1 {
+		return out.Bytes(), nil
+	}
+	return insertOrientation(out.Bytes(), orientation), nil
+}
+
+// jpegScanEnd returns the position of the marker ending the entropy coded
+// data that starts at pos, or the end of data when there is none. Stuffed
+// zero bytes and restart markers are part of the scan.
+func jpegScanEnd(data []byte, pos int) int {
+	for ; pos+1 < len(data); pos++ {
+		if data[pos] != 0xFF {
+			continue
+		}
+		next := data[pos+1]
+		if next != 0x00 && next != 0xFF && (next < 0xD0 || next > 0xD7) {
+			return pos
+		}
+	}
+	return len(data)
+}
+
+// JPEGOrientation returns the EXIF orientation of a JPEG file, 1 when it has
+// none or the file is not a JPEG
+func JPEGOrientation(data []byte) int {
+	if !bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		return 1
+	}
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xFF {
+		marker := data[pos+1]
+		if marker == 0xFF {
+			pos++
+			continue
+		}
+		// The EXIF block precedes the image data
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+		end := pos + 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+		if end > len(data) {
+			break
+		}
+		if payload := data[pos+4 : end]; marker == 0xE1 && bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+			if orientation := exifOrientation(payload[6:]); orientation != 0 {
+				return orientation
+			}
+		}
+		pos = end
+	}
+	return 1
+}
+
+// exifOrientation reads the orientation tag from the first IFD of an EXIF
+// TIFF block, 0 when there is none
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if value := int(order.Uint16(tiff[entry+8:])); value >= 1 && value <= 8 {
+				return value
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// insertOrientation adds an EXIF block holding only the orientation tag
+// after the SOI marker and the JFIF header, if there is one
+func insertOrientation(jpeg []byte, orientation int) []byte {
+	exif := []byte("\xFF\xE1\x00\x22Exif\x00\x00MM\x00\x2A\x00\x00\x00\x08" +
+		"\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x00\x00\x00" +
+		"\x00\x00\x00\x00")
+	exif[29] = byte(orientation)
+
+	at := 2
+	if len(jpeg) >= 6 && jpeg[2] == 0xFF && jpeg[3] == 0xE0 {
+		at = 4 + int(binary.BigEndian.Uint16(jpeg[4:]))
+	}
+	out := make([]byte, 0, len(jpeg)+len(exif))
+	out = append(out, jpeg[:at]...)
+	out = append(out, exif...)
+	return append(out, jpeg[at:]...)
+}
+
+// pngMetadataChunks can hold EXIF, XMP (in iTXt) or free text
+var pngMetadataChunks = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+func stripPNG(data []byte) ([]byte, error) {
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:8])
+	pos := 8
+	for pos < len(data) {
+		if pos+12 > len(data) {
+			return nil, ErrMalformedImage
+		}
+		end := pos + 12 + int(binary.BigEndian.Uint32(data[pos:]))
+		if end > len(data) || end < pos+12 {
+			return nil, ErrMalformedImage
+		}
+		if !pngMetadataChunks[string(data[pos+4:pos+8])] {
+			out.Write(data[pos:end])
+		}
+		pos = end
+	}
+	return out.Bytes(), nil
+}
+
+func stripWebP(data []byte) ([]byte, error) {
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:12])
+	pos := 12
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			return nil, ErrMalformedImage
+		}
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		end := pos + 8 + size + size%2
+		if end > len(data) {
+			return nil, ErrMalformedImage
+		}
+		switch string(data[pos : pos+4]) {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[pos:end]...)
+			if len(chunk) > 8 {
+				chunk[8] &^= 0x08 | 0x04 // EXIF and XMP flags
+			}
+			out.Write(chunk)
+		default:
+			out.Write(data[pos:end])
+		}
+		pos = end
+	}
+
+	result := out.Bytes()
+	binary.LittleEndian.PutUint32(result[4:], uint32(len(result)-8))
+	return result, nil
+}
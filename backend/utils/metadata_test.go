@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// exifSegment builds an APP1 EXIF segment with the orientation tag followed
+// by a marker string standing in for GPS data
+func exifSegment(orientation byte) []byte {
+	payload := []byte("Exif\x00\x00II\x2A\x00\x08\x00\x00\x00\x01\x00\x12\x01\x03\x00\x01\x00\x00\x00")
+	payload = append(payload, orientation, 0, 0, 0, 0, 0, 0, 0)
+	payload = append(payload, "GPS 55.75N 37.61E"...)
+	size := len(payload) + 2
+	return append([]byte{0xFF, 0xE1, byte(size >> 8), byte(size)}, payload...)
+}
+
+func testImage(t *testing.T, encode func(*bytes.Buffer, image.Image) error) []byte {
+	var buf bytes.Buffer
+	if err := encode(&buf, image.NewNRGBA(image.Rect(0, 0, 16, 12))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestStripJPEGMetadata(t *testing.T) {
+	clean := testImage(t, func(w *bytes.Buffer, img image.Image) error { return jpeg.Encode(w, img, nil) })
+
+	for _, orientation := range []byte{1, 6} {
+		xmp := append([]byte{0xFF, 0xE1, 0x00, 0x0F}, "http://ns.xmp"...)
+		input := append(append(append(append([]byte{}, clean[:2]...), exifSegment(orientation)...), xmp...), clean[2:]...)
+
+		stripped, err := StripImageMetadata(input)
+		if err != nil {
+			t.Fatalf("orientation %d: %v", orientation, err)
+		}
+		if bytes.Contains(stripped, []byte("GPS")) || bytes.Contains(stripped, []byte("xmp")) {
+			t.Errorf("orientation %d: metadata left in the file", orientation)
+		}
+		if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+			t.Errorf("orientation %d: stripped file does not decode: %v", orientation, err)
+		}
+
+		// Upright photos need no EXIF at all, rotated ones keep the tag
+		if orientation == 1 && !bytes.Equal(stripped, clean) {
+			t.Errorf("orientation 1: expected the file without metadata")
+		}
+		if orientation == 6 {
+			if got := exifOrientation(stripped[12:]); got != 6 {
+				t.Errorf("orientation 6: kept orientation %d", got)
+			}
+		}
+	}
+
+	if _, err := StripImageMetadata(clean[:30]); err != ErrMalformedImage {
+		t.Errorf("truncated JPEG: expected ErrMalformedImage, got %v", err)
+	}
+}
+
+func TestStripJPEGAppendedImages(t *testing.T) {
+	clean := testImage(t, func(w *bytes.Buffer, img image.Image) error { return jpeg.Encode(w, img, nil) })
+
+	// A phone photo: the MPF index in APP2 and a second JPEG with its own
+	// EXIF after the end of the primary image
+	mpf := append([]byte{0xFF, 0xE2, 0x00, 0x0C}, "MPF\x00MM\x00\x2A\x00\x00"...)
+	secondary := append(append(append([]byte{}, clean[:2]...), exifSegment(1)...), clean[2:]...)
+	input := append(append(append(append([]byte{}, clean[:2]...), mpf...), clean[2:]...), secondary...)
+
+	stripped, err := StripImageMetadata(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stripped, clean) {
+		t.Errorf("expected the primary image without metadata, got %d bytes of %d", len(stripped), len(clean))
+	}
+
+	// Scan data cut before the end of image is kept
+	truncated := clean[:len(clean)-2]
+	if stripped, err := StripImageMetadata(truncated); err != nil || !bytes.Equal(stripped, truncated) {
+		t.Errorf("truncated scan: got %d bytes, %v", len(stripped), err)
+	}
+}
+
+func TestStripPNGMetadata(t *testing.T) {
+	clean := testImage(t, func(w *bytes.Buffer, img image.Image) error { return png.Encode(w, img) })
+
+	// Chunks follow the 8 byte signature and the 25 byte IHDR chunk
+	text := []byte("\x00\x00\x00\x03tEXtGPS\x00\x00\x00\x00")
+	input := append(append(append([]byte{}, clean[:33]...), text...), clean[33:]...)
+
+	stripped, err := StripImageMetadata(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stripped, clean) {
+		t.Errorf("expected the file without the text chunk")
+	}
+}
+
+func TestStripWebPMetadata(t *testing.T) {
+	input := []byte("RIFF\x00\x00\x00\x00WEBPVP8X\x0A\x00\x00\x00\x0C\x00\x00\x00\x0F\x00\x00\x0B\x00\x00" +
+		"EXIF\x03\x00\x00\x00GPS\x00XMP \x02\x00\x00\x00<x")
+	want := []byte("RIFF\x16\x00\x00\x00WEBPVP8X\x0A\x00\x00\x00\x00\x00\x00\x00\x0F\x00\x00\x0B\x00\x00")
+
+	stripped, err := StripImageMetadata(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stripped, want) {
+		t.Errorf("got %q, want %q", stripped, want)
+	}
+}
+
+func TestStripOtherFiles(t *testing.T) {
+	data := []byte("plain text with GPS")
+	if stripped, err := StripImageMetadata(data); err != nil || !bytes.Equal(stripped, data) {
+		t.Errorf("non-image changed: %q, %v", stripped, err)
+	}
+}
@@ -0,0 +1,352 @@
+package utils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// oidcKeysRefetchInterval limits how often an unknown kid makes the provider
+// keys be downloaded again, so forged tokens can not hammer the issuer
+const oidcKeysRefetchInterval = time.Minute
+
+// OIDCProvider logs users in with an external OpenID Connect issuer using the
+// authorization code flow with PKCE. Endpoints and signing keys are discovered
+// from the issuer on first use.
+type OIDCProvider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// AutoProvision creates a local account on the first login of an
+	// identity that is not linked to any user yet
+	AutoProvision bool
+	// PostLoginURL is where the browser is sent once the callback is done
+	PostLoginURL string
+
+	client        *http.Client
+	mu            sync.Mutex
+	metadata      *oidcMetadata
+	keys          *Keyring
+	keysFetchedAt time.Time
+}
+
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCClaims are the ID token claims used to find or create the local user
+type OIDCClaims struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	PreferredUsername string
+	Name              string
+}
+
+// NewOIDCProviderFromEnv configures the provider from OIDC_ISSUER,
+// OIDC_CLIENT_ID, OIDC_CLIENT_SECRET, OIDC_REDIRECT_URL, OIDC_SCOPES,
+// OIDC_AUTO_PROVISION and OIDC_POST_LOGIN_URL. Without OIDC_ISSUER OIDC login
+// is disabled and nil is returned.
+func NewOIDCProviderFromEnv() (*OIDCProvider, error) {
+	issuer := strings.TrimSuffix(os.Getenv("OIDC_ISSUER"), "/")
+	if issuer == "" {
+		return nil, nil
+	}
+
+	p := &OIDCProvider{
+		Issuer:        issuer,
+		ClientID:      os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:        strings.Fields(os.Getenv("OIDC_SCOPES")),
+		AutoProvision: os.Getenv("OIDC_AUTO_PROVISION") == "true",
+		PostLoginURL:  os.Getenv("OIDC_POST_LOGIN_URL"),
+		client:        &http.Client{Timeout: 10 * time.Second},
+	}
+	if p.ClientID == "" || p.RedirectURL == "" {
+		return nil, fmt.Errorf("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required with OIDC_ISSUER")
+	}
+	if len(p.Scopes) == 0 {
+		p.Scopes = []string{"openid", "profile", "email"}
+	}
+	if !slices.Contains(p.Scopes, "openid") {
+		p.Scopes = append([]string{"openid"}, p.Scopes...)
+	}
+	if p.PostLoginURL == "" {
+		p.PostLoginURL = "/login"
+	}
+	return p, nil
+}
+
+// discover fetches the issuer metadata once
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	var metadata oidcMetadata
+	if err := p.getJSON(ctx, p.Issuer+"/.well-known/openid-configuration", &metadata); err != nil {
+		return nil, fmt.Errorf("OIDC discovery: %w", err)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != p.Issuer {
+		return nil, fmt.Errorf("OIDC discovery: issuer %q does not match %q", metadata.Issuer, p.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery: incomplete provider metadata")
+	}
+	p.metadata = &metadata
+	return p.metadata, nil
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// AuthCodeURL returns the provider URL the browser is sent to. The verifier
+// stays on our side, only its S256 challenge is sent.
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	authURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.ClientID)
+	query.Set("redirect_uri", p.RedirectURL)
+	query.Set("scope", strings.Join(p.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", PKCEChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+	return authURL.String(), nil
+}
+
+// Exchange redeems an authorization code at the token endpoint and returns
+// the raw ID token
+func (p *OIDCProvider) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("client_id", p.ClientID)
+	form.Set("code_verifier", verifier)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.ClientSecret != "" {
+		// client_secret_basic, the default client authentication of OIDC
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || out.Error != "" {
+		return "", fmt.Errorf("token endpoint: status %d: %s %s", resp.StatusCode, out.Error, out.ErrorDescription)
+	}
+	if out.IDToken == "" {
+		return "", fmt.Errorf("token response has no id_token")
+	}
+	return out.IDToken, nil
+}
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of
+// an ID token and returns its claims
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, rawToken, nonce string) (*OIDCClaims, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		keys, err := p.signingKeys(ctx, metadata, kid)
+		if err != nil {
+			return nil, err
+		}
+		return keys.Keyfunc(token)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, fmt.Errorf("ID token nonce does not match")
+	}
+	// A token issued to several clients must name us as the authorized party
+	if audience, _ := claims.GetAudience(); len(audience) > 1 {
+		if azp, _ := claims["azp"].(string); azp != p.ClientID {
+			return nil, fmt.Errorf("ID token is not issued to this client")
+		}
+	}
+
+	out := &OIDCClaims{Issuer: metadata.Issuer}
+	out.Subject, _ = claims["sub"].(string)
+	out.Email, _ = claims["email"].(string)
+	out.PreferredUsername, _ = claims["preferred_username"].(string)
+	out.Name, _ = claims["name"].(string)
+	if out.Subject == "" {
+		return nil, fmt.Errorf("ID token has no subject")
+	}
+	// Unverified addresses could belong to anyone
+	if verified, ok := claims["email_verified"].(bool); ok && !verified {
+		out.Email = ""
+	}
+	return out, nil
+}
+
+// signingKeys returns the provider keys, downloading them again when kid is
+// not among them, since providers rotate keys without notice
+func (p *OIDCProvider) signingKeys(ctx context.Context, metadata *oidcMetadata, kid string) (*Keyring, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keys != nil {
+		if _, ok := p.keys.keys[kid]; ok || time.Since(p.keysFetchedAt) < oidcKeysRefetchInterval {
+			return p.keys, nil
+		}
+	}
+
+	var set JWKSet
+	if err := p.getJSON(ctx, metadata.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("OIDC keys: %w", err)
+	}
+	keys := &Keyring{keys: map[string]*JWTKey{}}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parseJWK(jwk)
+		if err != nil {
+			continue
+		}
+		keys.keys[jwk.Kid] = key
+	}
+	p.keys, p.keysFetchedAt = keys, time.Now()
+	return keys, nil
+}
+
+// parseJWK reads an RSA, P-256 or Ed25519 public key published by a provider
+func parseJWK(jwk JWK) (*JWTKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	key := &JWTKey{ID: jwk.Kid}
+	switch {
+	case jwk.Kty == "RSA":
+		n, errN := decode(jwk.N)
+		e, errE := decode(jwk.E)
+		if errN != nil || errE != nil || len(e) > 4 {
+			return nil, fmt.Errorf("malformed RSA key")
+		}
+		key.Method = jwt.SigningMethodRS256
+		key.Public = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case jwk.Kty == "EC" && jwk.Crv == "P-256":
+		x, errX := decode(jwk.X)
+		y, errY := decode(jwk.Y)
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("malformed EC key")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, fmt.Errorf("EC point is not on the curve")
+		}
+		key.Method = jwt.SigningMethodES256
+		key.Public = pub
+	case jwk.Kty == "OKP" && jwk.Crv == "Ed25519":
+		x, err := decode(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("malformed Ed25519 key")
+		}
+		key.Method = jwt.SigningMethodEdDSA
+		key.Public = ed25519.PublicKey(x)
+	default:
+		return nil, fmt.Errorf("unsupported key type %s %s", jwk.Kty, jwk.Crv)
+	}
+	if jwk.Alg != "" && jwk.Alg != key.Method.Alg() {
+		return nil, fmt.Errorf("key algorithm %s does not match key type", jwk.Alg)
+	}
+	return key, nil
+}
+
+// RandomURLString returns n random bytes in URL-safe base64, used for OIDC
+// state, nonce and PKCE verifier
+func RandomURLString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// PKCEChallenge is the S256 code challenge of a verifier (RFC 7636)
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
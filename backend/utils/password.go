@@ -2,28 +2,120 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
+	"fmt"
 	"os"
+	"strings"
 
 	"golang.org/x/crypto/argon2"
 )
 
-func GenerateSalt() (string, error) {
-    salt := make([]byte, 16)
+// Argon2id parameters for new hashes. Hashes made with other parameters still
+// verify and are upgraded on the next login, see NeedsRehash.
+const (
+	argon2Time    = 1
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+func GenerateSalt() ([]byte, error) {
+    salt := make([]byte, argon2SaltLen)
     _, err := rand.Read(salt)
     if err != nil {
-        return "", err
+        return nil, err
+    }
+    return salt, nil
+}
+
+// HashPassword hashes a password with a fresh random salt and returns it in
+// PHC string format: $argon2id$v=19$m=65536,t=1,p=4$<salt>$<hash>
+func HashPassword(password string) (string, error) {
+    salt, err := GenerateSalt()
+    if err != nil {
+        return "", fmt.Errorf("error generating salt: %w", err)
+    }
+    hash := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+    return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+        argon2.Version, argon2Memory, argon2Time, argon2Threads,
+        base64.RawStdEncoding.EncodeToString(salt),
+        base64.RawStdEncoding.EncodeToString(hash)), nil
+}
+
+// CheckPassword verifies a password against a PHC hash or, for accounts
+// created before per-user salts, against the legacy PASSWORD_SALT hash
+func CheckPassword(password, hashedPassword string) (bool) {
+    // Accounts created through OIDC have no local password
+    if hashedPassword == "" {
+        return false
+    }
+    if !strings.HasPrefix(hashedPassword, "$") {
+        computedHash := LegacyHashPassword(password)
+        return subtle.ConstantTimeCompare([]byte(computedHash), []byte(hashedPassword)) == 1
+    }
+
+    params, salt, hash, err := parsePHC(hashedPassword)
+    if err != nil {
+        return false
+    }
+    computedHash := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(hash)))
+    return subtle.ConstantTimeCompare(computedHash, hash) == 1
+}
+
+// NeedsRehash reports whether a stored hash is a legacy one or uses other
+// parameters than HashPassword does now
+func NeedsRehash(hashedPassword string) bool {
+    params, salt, hash, err := parsePHC(hashedPassword)
+    if err != nil {
+        return true
     }
-    return base64.RawStdEncoding.EncodeToString(salt), nil
+    return params != (argon2Params{argon2Time, argon2Memory, argon2Threads}) ||
+        len(salt) != argon2SaltLen || len(hash) != argon2KeyLen
 }
 
-func HashPassword(password string) string {
+// LegacyHashPassword is the old scheme with one salt for every user. New
+// hashes are never made with it; it is exported for migration tests.
+func LegacyHashPassword(password string) string {
 	var salt string = os.Getenv("PASSWORD_SALT")
     hash := argon2.IDKey([]byte(password), []byte(salt), 1, 64*1024, 4, 32)
     return base64.RawStdEncoding.EncodeToString(hash)
 }
 
-func CheckPassword(password, hashedPassword string) (bool) {
-    computedHash := HashPassword(password)
-    return computedHash == hashedPassword
-}
\ No newline at end of file
+type argon2Params struct {
+    time    uint32
+    memory  uint32
+    threads uint8
+}
+
+func parsePHC(encoded string) (argon2Params, []byte, []byte, error) {
+    var params argon2Params
+
+    // "", "argon2id", "v=19", "m=..,t=..,p=..", salt, hash
+    parts := strings.Split(encoded, "$")
+    if len(parts) != 6 || parts[1] != "argon2id" {
+        return params, nil, nil, fmt.Errorf("not an argon2id PHC string")
+    }
+
+    var version int
+    if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+        return params, nil, nil, fmt.Errorf("unsupported argon2 version")
+    }
+    if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+        return params, nil, nil, fmt.Errorf("invalid argon2 parameters: %w", err)
+    }
+    if params.time == 0 || params.memory == 0 || params.threads == 0 {
+        return params, nil, nil, fmt.Errorf("invalid argon2 parameters")
+    }
+
+    salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+    if err != nil {
+        return params, nil, nil, fmt.Errorf("invalid salt: %w", err)
+    }
+    hash, err := base64.RawStdEncoding.DecodeString(parts[5])
+    if err != nil || len(hash) == 0 {
+        return params, nil, nil, fmt.Errorf("invalid hash")
+    }
+    return params, salt, hash, nil
+}
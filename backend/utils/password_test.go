@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+func TestPasswordRoundTrip(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=1,p=4$") {
+		t.Errorf("Unexpected PHC string %q", hash)
+	}
+	if !CheckPassword("correct horse", hash) {
+		t.Errorf("Password does not verify against its own hash")
+	}
+	if CheckPassword("correct horse!", hash) || CheckPassword("", hash) {
+		t.Errorf("Wrong password verifies")
+	}
+	if NeedsRehash(hash) {
+		t.Errorf("Fresh hash needs a rehash")
+	}
+
+	// Every hash gets its own salt
+	other, _ := HashPassword("correct horse")
+	if other == hash {
+		t.Errorf("Two hashes of one password are equal")
+	}
+}
+
+func TestPasswordMalformedHashes(t *testing.T) {
+	hash, _ := HashPassword("secret")
+	parts := strings.Split(hash, "$")
+	malformed := []string{
+		"",
+		"$",
+		"$argon2id",
+		"$argon2id$v=19",
+		"$argon2id$v=19$m=65536,t=1,p=4",
+		"$argon2id$v=19$m=65536,t=1,p=4$" + parts[4],
+		"$argon2id$v=19$m=65536,t=1,p=4$" + parts[4] + "$",
+		"$argon2i$v=19$m=65536,t=1,p=4$" + parts[4] + "$" + parts[5],
+		"$argon2id$v=16$m=65536,t=1,p=4$" + parts[4] + "$" + parts[5],
+		"$argon2id$v=19$m=0,t=1,p=4$" + parts[4] + "$" + parts[5],
+		"$argon2id$v=19$m=65536,t=0,p=4$" + parts[4] + "$" + parts[5],
+		"$argon2id$v=19$m=65536,t=1,p=0$" + parts[4] + "$" + parts[5],
+		"$argon2id$v=19$m=x,t=1,p=4$" + parts[4] + "$" + parts[5],
+		"$argon2id$v=19$m=65536,t=1,p=4$!!!$" + parts[5],
+		"$argon2id$v=19$m=65536,t=1,p=4$" + parts[4] + "$!!!",
+		"$argon2id$v=19$m=65536,t=1,p=4$" + parts[4] + "$" + parts[5] + "$extra",
+		hash[:len(hash)-10],
+	}
+	for _, encoded := range malformed {
+		if CheckPassword("secret", encoded) {
+			t.Errorf("Malformed hash %q verifies", encoded)
+		}
+		if !NeedsRehash(encoded) {
+			t.Errorf("Malformed hash %q does not need a rehash", encoded)
+		}
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	salt := make([]byte, argon2SaltLen)
+	encode := func(memory, time uint32, threads uint8, keyLen uint32) string {
+		hash := argon2.IDKey([]byte("secret"), salt, time, memory, threads, keyLen)
+		return fmt.Sprintf("$argon2id$v=19$m=%d,t=%d,p=%d$%s$%s", memory, time, threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(hash))
+	}
+
+	current := encode(argon2Memory, argon2Time, argon2Threads, argon2KeyLen)
+	if NeedsRehash(current) {
+		t.Errorf("Hash with the current parameters needs a rehash")
+	}
+	// Older parameters still verify, but are upgraded
+	for _, outdated := range []string{
+		encode(32*1024, argon2Time, argon2Threads, argon2KeyLen),
+		encode(argon2Memory, 2, argon2Threads, argon2KeyLen),
+		encode(argon2Memory, argon2Time, 2, argon2KeyLen),
+		encode(argon2Memory, argon2Time, argon2Threads, 16),
+	} {
+		if !CheckPassword("secret", outdated) {
+			t.Errorf("Hash %q does not verify", outdated)
+		}
+		if !NeedsRehash(outdated) {
+			t.Errorf("Hash %q does not need a rehash", outdated)
+		}
+	}
+}
+
+func TestPasswordLegacyHash(t *testing.T) {
+	t.Setenv("PASSWORD_SALT", "legacy_salt")
+	legacy := base64.RawStdEncoding.EncodeToString(argon2.IDKey([]byte("secret"), []byte("legacy_salt"), 1, 64*1024, 4, 32))
+
+	if LegacyHashPassword("secret") != legacy {
+		t.Errorf("Legacy hash changed")
+	}
+	if !CheckPassword("secret", legacy) {
+		t.Errorf("Legacy hash does not verify")
+	}
+	if CheckPassword("wrong", legacy) {
+		t.Errorf("Wrong password verifies against a legacy hash")
+	}
+	if !NeedsRehash(legacy) {
+		t.Errorf("Legacy hash does not need a rehash")
+	}
+
+	// The legacy scheme depends on PASSWORD_SALT
+	t.Setenv("PASSWORD_SALT", "other_salt")
+	if CheckPassword("secret", legacy) {
+		t.Errorf("Legacy hash verifies with another PASSWORD_SALT")
+	}
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const redactedValue = "[REDACTED]"
+
+// Redact formats a struct like %+v but replaces every field tagged
+// `redact:"true"` with [REDACTED], so request bodies can be logged safely.
+// Pointers are followed; other values are formatted as usual.
+func Redact(v interface{}) string {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return "<nil>"
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return fmt.Sprintf("%+v", v)
+	}
+
+	var out strings.Builder
+	out.WriteByte('{')
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if out.Len() > 1 {
+			out.WriteByte(' ')
+		}
+		out.WriteString(field.Name)
+		out.WriteByte(':')
+		if field.Tag.Get("redact") == "true" {
+			out.WriteString(redactedValue)
+			continue
+		}
+		fmt.Fprintf(&out, "%+v", value.Field(i).Interface())
+	}
+	out.WriteByte('}')
+	return out.String()
+}
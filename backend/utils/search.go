@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// BuildTSQuery converts a user search string into to_tsquery syntax.
+// Words are AND-ed, "quoted text" becomes a phrase (<->) and a trailing *
+// turns a word into a prefix match. Everything except letters and digits is
+// dropped, so the result is always a well-formed tsquery.
+func BuildTSQuery(q string) (string, error) {
+	var terms []string
+
+	for i, part := range strings.Split(q, `"`) {
+		words := splitSearchWords(part)
+		if i%2 == 1 {
+			// Inside quotes: the words form a phrase
+			if len(words) > 0 {
+				terms = append(terms, "("+strings.Join(words, " <-> ")+")")
+			}
+			continue
+		}
+		terms = append(terms, words...)
+	}
+
+	if len(terms) == 0 {
+		return "", fmt.Errorf("search query has no words")
+	}
+	return strings.Join(terms, " & "), nil
+}
+
+// splitSearchWords splits text into lowercase lexemes. A word directly
+// followed by * is marked as a prefix match.
+func splitSearchWords(text string) []string {
+	var words []string
+	var current strings.Builder
+
+	flush := func(prefix bool) {
+		if current.Len() > 0 {
+			word := current.String()
+			if prefix {
+				word += ":*"
+			}
+			words = append(words, word)
+			current.Reset()
+		}
+	}
+
+	for _, ch := range text {
+		switch {
+		case unicode.IsLetter(ch) || unicode.IsDigit(ch):
+			current.WriteRune(unicode.ToLower(ch))
+		case ch == '*':
+			flush(true)
+		default:
+			flush(false)
+		}
+	}
+	flush(false)
+
+	return words
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// PersonalTokenPrefix marks personal access tokens, so they are told apart
+// from JWTs in the Authorization header and are easy to spot in leaked logs
+const PersonalTokenPrefix = "rhp_"
+
+// personalTokenDisplayLen is how much of a token is kept in clear text
+const personalTokenDisplayLen = len(PersonalTokenPrefix) + 8
+
+// GeneratePersonalToken returns a new random token, its display prefix and
+// the hash to store
+func GeneratePersonalToken() (token, prefix, hash string, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+	token = PersonalTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return token, token[:personalTokenDisplayLen], HashPersonalToken(token), nil
+}
+
+// HashPersonalToken hashes a token for lookup. Tokens carry 256 random bits,
+// so a fast unsalted hash is enough.
+func HashPersonalToken(token string) string {
+	return sha256Hex(token)
+}
+
+// GenerateInviteCode returns a new invite code, its display prefix and the
+// hash to store
+func GenerateInviteCode() (code, prefix, hash string, err error) {
+	secret := make([]byte, 15)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+	code = base64.RawURLEncoding.EncodeToString(secret)
+	return code, code[:6], HashInviteCode(code), nil
+}
+
+func HashInviteCode(code string) string {
+	return sha256Hex(strings.TrimSpace(code))
+}
+
+// GenerateResetToken returns a new password reset token and the hash to store
+func GenerateResetToken() (token, hash string, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(secret)
+	return token, HashResetToken(token), nil
+}
+
+func HashResetToken(token string) string {
+	return sha256Hex(strings.TrimSpace(token))
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func IsPersonalToken(token string) bool {
+	return strings.HasPrefix(token, PersonalTokenPrefix)
+}
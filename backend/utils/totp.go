@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters, the defaults every authenticator app understands
+const (
+	TOTPPeriod    = 30
+	TOTPDigits    = 6
+	totpSecretLen = 20
+	// totpSkew is how many periods before and after now are accepted, to
+	// allow for clock drift and slow typing
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a random secret in base32, as shown to the user
+// and put into the otpauth URI
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, totpSecretLen)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TOTPURI builds the otpauth:// URI that authenticator apps import from a QR
+// code
+func TOTPURI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(TOTPDigits))
+	query.Set("period", fmt.Sprint(TOTPPeriod))
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// HOTP computes an RFC 4226 one-time password
+func HOTP(key []byte, counter uint64, digits int, algorithm func() hash.Hash) string {
+	mac := hmac.New(algorithm, key)
+	binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, code%mod)
+}
+
+// TOTPCounter is the RFC 6238 time step of t
+func TOTPCounter(t time.Time, period int64) uint64 {
+	return uint64(t.Unix() / period)
+}
+
+// TOTP computes an RFC 6238 time-based one-time password
+func TOTP(key []byte, t time.Time, period int64, digits int, algorithm func() hash.Hash) string {
+	return HOTP(key, TOTPCounter(t, period), digits, algorithm)
+}
+
+// ValidateTOTP checks a code against a base32 secret with the default
+// parameters. Codes of counters up to lastCounter were already used and are
+// refused, so a code cannot be replayed. On success the matched counter is
+// returned, to be stored as the new lastCounter.
+func ValidateTOTP(secret, code string, t time.Time, lastCounter uint64) (uint64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != TOTPDigits {
+		return 0, false
+	}
+
+	now := TOTPCounter(t, TOTPPeriod)
+	for i := -totpSkew; i <= totpSkew; i++ {
+		counter := uint64(int64(now) + int64(i))
+		if counter <= lastCounter {
+			continue
+		}
+		expected := HOTP(key, counter, TOTPDigits, sha1.New)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+// recoveryCodeAlphabet avoids characters that are easy to confuse
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// GenerateRecoveryCode returns a random code like "k4tz-9qmw-hr2c"
+func GenerateRecoveryCode() (string, error) {
+	// Bytes above the largest multiple of the alphabet size are skipped, so
+	// every character is equally likely
+	limit := byte(256 / len(recoveryCodeAlphabet) * len(recoveryCodeAlphabet))
+
+	var code strings.Builder
+	buf := make([]byte, 1)
+	for n := 0; n < 12; {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		if buf[0] >= limit {
+			continue
+		}
+		if n > 0 && n%4 == 0 {
+			code.WriteByte('-')
+		}
+		code.WriteByte(recoveryCodeAlphabet[int(buf[0])%len(recoveryCodeAlphabet)])
+		n++
+	}
+	return code.String(), nil
+}
+
+// HashRecoveryCode hashes a recovery code for lookup. Codes carry about 59
+// random bits and are only tried behind a password and the login throttle,
+// so a fast unsalted hash is enough.
+func HashRecoveryCode(code string) string {
+	return sha256Hex(NormalizeRecoveryCode(code))
+}
+
+// NormalizeRecoveryCode makes codes typed with other case or without dashes
+// comparable
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, " ", "")
+	code = strings.ReplaceAll(code, "-", "")
+	var out strings.Builder
+	for i, ch := range code {
+		if i > 0 && i%4 == 0 {
+			out.WriteByte('-')
+		}
+		out.WriteRune(ch)
+	}
+	return out.String()
+}
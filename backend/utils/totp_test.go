@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238, Appendix B. Each algorithm uses the ASCII seed
+// "12345678901234567890" repeated to its key length.
+func TestTOTPRFC6238Vectors(t *testing.T) {
+	seeds := map[string][]byte{
+		"SHA1":   []byte("12345678901234567890"),
+		"SHA256": []byte("12345678901234567890123456789012"),
+		"SHA512": []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+	algorithms := map[string]func() hash.Hash{
+		"SHA1":   sha1.New,
+		"SHA256": sha256.New,
+		"SHA512": sha512.New,
+	}
+
+	vectors := []struct {
+		unix int64
+		algo string
+		want string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1111111109, "SHA256", "68084774"},
+		{1111111109, "SHA512", "25091201"},
+		{1111111111, "SHA1", "14050471"},
+		{1111111111, "SHA256", "67062674"},
+		{1111111111, "SHA512", "99943326"},
+		{1234567890, "SHA1", "89005924"},
+		{1234567890, "SHA256", "91819424"},
+		{1234567890, "SHA512", "93441116"},
+		{2000000000, "SHA1", "69279037"},
+		{2000000000, "SHA256", "90698825"},
+		{2000000000, "SHA512", "38618901"},
+		{20000000000, "SHA1", "65353130"},
+		{20000000000, "SHA256", "77737706"},
+		{20000000000, "SHA512", "47863826"},
+	}
+
+	for _, v := range vectors {
+		got := TOTP(seeds[v.algo], time.Unix(v.unix, 0), 30, 8, algorithms[v.algo])
+		if got != v.want {
+			t.Errorf("TOTP %s at %d: expected %s, got %s", v.algo, v.unix, v.want, got)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	// Base32 of the RFC 6238 SHA1 seed
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	key := []byte("12345678901234567890")
+	now := time.Unix(1111111111, 0)
+
+	code := TOTP(key, now, TOTPPeriod, TOTPDigits, sha1.New)
+	counter, ok := ValidateTOTP(secret, code, now, 0)
+	if !ok || counter != TOTPCounter(now, TOTPPeriod) {
+		t.Fatalf("Current code rejected")
+	}
+
+	// One period of drift either way is accepted
+	if _, ok := ValidateTOTP(secret, code, now.Add(TOTPPeriod*time.Second), 0); !ok {
+		t.Errorf("Code of the previous period rejected")
+	}
+	if _, ok := ValidateTOTP(secret, code, now.Add(-TOTPPeriod*time.Second), 0); !ok {
+		t.Errorf("Code of the next period rejected")
+	}
+	if _, ok := ValidateTOTP(secret, code, now.Add(3*TOTPPeriod*time.Second), 0); ok {
+		t.Errorf("Stale code accepted")
+	}
+
+	// A used code cannot be replayed
+	if _, ok := ValidateTOTP(secret, code, now, counter); ok {
+		t.Errorf("Replayed code accepted")
+	}
+	wrong := code[:5] + string('0'+(code[5]-'0'+1)%10)
+	if _, ok := ValidateTOTP(secret, wrong, now, 0); ok {
+		t.Errorf("Wrong code accepted")
+	}
+}
+
+func TestRecoveryCodeFormat(t *testing.T) {
+	code, err := GenerateRecoveryCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != 14 || code[4] != '-' || code[9] != '-' {
+		t.Fatalf("Unexpected recovery code format: %q", code)
+	}
+	if got := NormalizeRecoveryCode(" " + code[0:4] + code[5:9] + code[10:] + " "); got != code {
+		t.Errorf("Normalize: expected %q, got %q", code, got)
+	}
+	if HashRecoveryCode(strings.ToUpper(code)) != HashRecoveryCode(code) || len(HashRecoveryCode(code)) != 64 {
+		t.Errorf("Hash of a recovery code depends on its case")
+	}
+}
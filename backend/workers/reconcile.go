@@ -0,0 +1,176 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+
+	"rulehub/models"
+	"rulehub/schemas"
+	"rulehub/storage"
+
+	"gorm.io/gorm"
+)
+
+// referencedKeys returns the subset of keys that a live media row or a user
+// avatar points at. Media of articles in the trash counts as referenced, so
+// they can be restored.
+func referencedKeys(db *gorm.DB, keys []string) (map[string]bool, error) {
+	var found, avatars []string
+	if err := db.Model(&models.Media{}).Where("s3_key IN ?", keys).Distinct().Pluck("s3_key", &found).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.User{}).Where("avatar_key IN ?", keys).Distinct().Pluck("avatar_key", &avatars).Error; err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool, len(found)+len(avatars))
+	for _, key := range append(found, avatars...) {
+		referenced[key] = true
+	}
+	return referenced, nil
+}
+
+// DemoteUnreferenced marks those of the given objects that no article uses any
+// more as temporary, so the sweeper deletes them. It returns the demoted keys.
+func DemoteUnreferenced(db *gorm.DB, store storage.Storage, keys []string) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	referenced, err := referencedKeys(db, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var demoted []string
+	for _, key := range keys {
+		if referenced[key] {
+			continue
+		}
+		if err := store.MarkTemporary(context.Background(), key); err != nil {
+			log.Printf("Error demoting %v: %v", key, err)
+			continue
+		}
+		demoted = append(demoted, key)
+	}
+	return demoted, nil
+}
+
+// DeleteUnreferenced deletes those of the given objects that nothing points at
+// any more, together with their variants. It returns the deleted keys.
+func DeleteUnreferenced(db *gorm.DB, store storage.Storage, keys []string) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	referenced, err := referencedKeys(db, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	for _, key := range keys {
+		if referenced[key] || slices.Contains(deleted, key) {
+			continue
+		}
+		if err := DeleteVariants(context.Background(), db, store, key); err != nil {
+			return deleted, err
+		}
+		if err := store.Delete(context.Background(), key); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, key)
+	}
+	return deleted, nil
+}
+
+// ReconcileMedia compares the storage with the media table. Permanent objects
+// without a live media row or avatar are handled according to mode; media rows whose
+// object is missing are reported as dangling.
+func ReconcileMedia(ctx context.Context, db *gorm.DB, store storage.Storage, mode string) (schemas.MediaReconcileReport, error) {
+	report := schemas.MediaReconcileReport{
+		Mode:         mode,
+		Unreferenced: []string{},
+		DanglingRows: []schemas.DanglingMedia{},
+	}
+
+	var media []models.Media
+	if err := db.Find(&media).Error; err != nil {
+		return report, err
+	}
+	var avatars []string
+	if err := db.Model(&models.User{}).Where("avatar_key <> ''").Pluck("avatar_key", &avatars).Error; err != nil {
+		return report, err
+	}
+	referenced := make(map[string]bool, len(media)+len(avatars))
+	for _, m := range media {
+		referenced[m.S3Key] = true
+	}
+	for _, key := range avatars {
+		referenced[key] = true
+	}
+
+	inBucket := make(map[string]bool)
+	err := store.List(ctx, "", func(object storage.ObjectInfo) error {
+		report.ScannedObjects++
+		inBucket[object.Key] = true
+
+		if referenced[object.Key] || strings.HasPrefix(object.Key, storage.VariantsPrefix) {
+			return nil
+		}
+		// Fresh uploads are not attached yet, the sweeper owns them
+		permanent, err := store.IsPermanent(ctx, object.Key)
+		if err != nil {
+			log.Printf("Reconcile: %v: %v", object.Key, err)
+			report.Errors++
+			return nil
+		}
+		if !permanent {
+			return nil
+		}
+		// The object may have been attached since the media table was read
+		if recheck, err := referencedKeys(db, []string{object.Key}); err != nil || recheck[object.Key] {
+			return nil
+		}
+		report.Unreferenced = append(report.Unreferenced, object.Key)
+
+		switch mode {
+		case "demote":
+			if err := store.MarkTemporary(ctx, object.Key); err != nil {
+				log.Printf("Reconcile: %v: %v", object.Key, err)
+				report.Errors++
+				return nil
+			}
+			report.Demoted++
+		case "delete":
+			if err := DeleteVariants(ctx, db, store, object.Key); err != nil {
+				log.Printf("Reconcile: variants of %v: %v", object.Key, err)
+				report.Errors++
+				return nil
+			}
+			if err := store.Delete(ctx, object.Key); err != nil {
+				log.Printf("Reconcile: %v: %v", object.Key, err)
+				report.Errors++
+				return nil
+			}
+			report.Deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	for _, m := range media {
+		if !inBucket[m.S3Key] {
+			report.DanglingRows = append(report.DanglingRows, schemas.DanglingMedia{
+				MediaID:   m.ID.String(),
+				ArticleID: m.ArticleID,
+				S3Key:     m.S3Key,
+			})
+		}
+	}
+
+	log.Printf("Media reconcile (%s): scanned=%d unreferenced=%d demoted=%d deleted=%d dangling=%d errors=%d",
+		mode, report.ScannedObjects, len(report.Unreferenced), report.Demoted, report.Deleted, len(report.DanglingRows), report.Errors)
+	return report, nil
+}
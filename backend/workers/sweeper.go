@@ -0,0 +1,178 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"rulehub/models"
+	"rulehub/schemas"
+	"rulehub/storage"
+	"rulehub/utils"
+
+	"gorm.io/gorm"
+)
+
+// MediaSweeper periodically deletes uploads that were never attached to an
+// article: objects without the permanent tag that are older than TTL
+type MediaSweeper struct {
+	DB       *gorm.DB
+	Storage  storage.Storage
+	TTL      time.Duration
+	Interval time.Duration
+
+	sweepMu      sync.Mutex // serializes passes
+	mu           sync.Mutex // guards the fields below
+	running      bool
+	last         *schemas.SweepStats
+	totalDeleted int
+	runs         int
+}
+
+// NewMediaSweeper configures a sweeper from MEDIA_TEMP_TTL and
+// MEDIA_SWEEP_INTERVAL (both in seconds)
+func NewMediaSweeper(db *gorm.DB, store storage.Storage) *MediaSweeper {
+	sweeper := &MediaSweeper{
+		DB:       db,
+		Storage:  store,
+		TTL:      utils.GetDurationEnv("MEDIA_TEMP_TTL", 24*time.Hour),
+		Interval: utils.GetDurationEnv("MEDIA_SWEEP_INTERVAL", time.Hour),
+	}
+	if sweeper.TTL <= utils.GetPresignedLifetime() {
+		log.Printf("MEDIA_TEMP_TTL (%v) is not longer than the presigned URL lifetime, uploads in progress may be swept", sweeper.TTL)
+	}
+	return sweeper
+}
+
+// Run sweeps every Interval until ctx is cancelled
+func (s *MediaSweeper) Run(ctx context.Context) {
+	log.Printf("Media sweeper started: ttl=%v interval=%v", s.TTL, s.Interval)
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.SweepOnce(ctx); err != nil {
+				log.Printf("Media sweep failed: %v", err)
+			}
+		}
+	}
+}
+
+// SweepOnce runs a single pass. Concurrent calls wait for each other.
+func (s *MediaSweeper) SweepOnce(ctx context.Context) (schemas.SweepStats, error) {
+	s.sweepMu.Lock()
+	defer s.sweepMu.Unlock()
+
+	s.mu.Lock()
+	s.running = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	stats, err := s.sweep(ctx)
+
+	s.mu.Lock()
+	s.last = &stats
+	s.totalDeleted += stats.Deleted
+	s.runs++
+	s.mu.Unlock()
+
+	log.Printf("Media sweep done: scanned=%d deleted=%d kept=%d errors=%d",
+		stats.Scanned, stats.Deleted, stats.Kept, stats.Errors)
+	return stats, err
+}
+
+func (s *MediaSweeper) sweep(ctx context.Context) (schemas.SweepStats, error) {
+	stats := schemas.SweepStats{StartedAt: time.Now()}
+	cutoff := stats.StartedAt.Add(-s.TTL)
+
+	err := s.Storage.List(ctx, "", func(object storage.ObjectInfo) error {
+		stats.Scanned++
+
+		// Variants go together with their original
+		if strings.HasPrefix(object.Key, storage.VariantsPrefix) {
+			stats.Kept++
+			return nil
+		}
+		if object.LastModified.After(cutoff) {
+			stats.Kept++
+			return nil
+		}
+
+		permanent, err := s.Storage.IsPermanent(ctx, object.Key)
+		if err != nil {
+			log.Printf("Sweeper: %v: %v", object.Key, err)
+			stats.Errors++
+			return nil
+		}
+		if permanent {
+			stats.Kept++
+			return nil
+		}
+
+		// Never delete an object an article or a profile still points at,
+		// whatever its tags
+		refs, err := referencedKeys(s.DB, []string{object.Key})
+		if err != nil {
+			log.Printf("Sweeper: %v: %v", object.Key, err)
+			stats.Errors++
+			return nil
+		}
+		if refs[object.Key] {
+			stats.Kept++
+			return nil
+		}
+
+		if err := DeleteVariants(ctx, s.DB, s.Storage, object.Key); err != nil {
+			log.Printf("Sweeper: variants of %v: %v", object.Key, err)
+			stats.Errors++
+			return nil
+		}
+		if err := s.Storage.Delete(ctx, object.Key); err != nil {
+			log.Printf("Sweeper: %v: %v", object.Key, err)
+			stats.Errors++
+			return nil
+		}
+		stats.Deleted++
+		return nil
+	})
+	if err != nil {
+		stats.FinishedAt = time.Now()
+		return stats, err
+	}
+
+	// Records of uploads that were never attached are only needed until the
+	// sweeper owns their object
+	if err := s.DB.Unscoped().
+		Where("expires_at < ? AND file_id NOT IN (SELECT s3_key FROM media) AND file_id NOT IN (SELECT avatar_key FROM users)", cutoff).
+		Delete(&models.Upload{}).Error; err != nil {
+		log.Printf("Sweeper: pruning upload records: %v", err)
+		stats.Errors++
+	}
+
+	stats.FinishedAt = time.Now()
+	return stats, nil
+}
+
+// Status returns a snapshot of the sweeper for the admin API
+func (s *MediaSweeper) Status() schemas.SweeperStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return schemas.SweeperStatus{
+		TTLSeconds:      int(s.TTL.Seconds()),
+		IntervalSeconds: int(s.Interval.Seconds()),
+		Running:         s.running,
+		Runs:            s.runs,
+		TotalDeleted:    s.totalDeleted,
+		LastRun:         s.last,
+	}
+}
@@ -0,0 +1,185 @@
+package workers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"log"
+
+	"rulehub/models"
+	"rulehub/storage"
+	"rulehub/utils"
+
+	"gorm.io/gorm"
+)
+
+// VariantWidths are the widths responsive copies of images are made in
+var VariantWidths = []int{320, 800, 1600}
+
+const (
+	variantQuality = 80
+	variantQueue   = 256
+	// Larger images are not decoded at all, a small file may still unpack
+	// into gigabytes of pixels
+	maxVariantSourcePixels = 50_000_000
+)
+
+// variantSourceTypes are the images variants are made of. GIFs are left
+// alone so that animations survive.
+var variantSourceTypes = map[string]bool{"image/jpeg": true, "image/png": true, "image/webp": true}
+
+// VariantGenerator makes resized JPEG and WebP copies of media once they
+// become permanent, in the background
+type VariantGenerator struct {
+	DB      *gorm.DB
+	Storage storage.Storage
+
+	webp  string // cwebp binary, "" when WebP variants are not made
+	queue chan string
+}
+
+func NewVariantGenerator(db *gorm.DB, store storage.Storage) *VariantGenerator {
+	generator := &VariantGenerator{
+		DB:      db,
+		Storage: store,
+		webp:    utils.WebPEncoder(),
+		queue:   make(chan string, variantQueue),
+	}
+	if generator.webp == "" {
+		log.Printf("cwebp not found, only JPEG variants of media are made")
+	}
+	return generator
+}
+
+// Enqueue schedules variants of the given objects. It never blocks: when the
+// queue is full the keys are dropped and their media is served as is.
+func (g *VariantGenerator) Enqueue(keys ...string) {
+	for _, key := range keys {
+		select {
+		case g.queue <- key:
+		default:
+			log.Printf("Variant queue is full, %v skipped", key)
+		}
+	}
+}
+
+// Run generates queued variants until ctx is cancelled
+func (g *VariantGenerator) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case key := <-g.queue:
+			if err := g.Generate(ctx, key); err != nil {
+				log.Printf("Error generating variants of %v: %v", key, err)
+			}
+		}
+	}
+}
+
+// Generate makes the variants of one object and records them. Objects that
+// are not images, already have variants or are smaller than every variant
+// width are skipped.
+func (g *VariantGenerator) Generate(ctx context.Context, key string) error {
+	var existing int64
+	if err := g.DB.Model(&models.MediaVariant{}).Where("source_key = ?", key).Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		return nil
+	}
+
+	object, info, err := g.Storage.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+	if !variantSourceTypes[info.ContentType] {
+		return nil
+	}
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("reading image header: %w", err)
+	}
+	if config.Width*config.Height > maxVariantSourcePixels {
+		log.Printf("Variants of %v skipped: %dx%d is too large", key, config.Width, config.Height)
+		return nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("decoding image: %w", err)
+	}
+	// Originals keep their EXIF orientation, variants have none
+	img = utils.ApplyOrientation(img, utils.JPEGOrientation(data))
+
+	var variants []models.MediaVariant
+	for _, width := range VariantWidths {
+		// Upscaled copies would only be larger and blurrier than the original
+		if width >= img.Bounds().Dx() {
+			break
+		}
+		resized := utils.ResizeToWidth(img, width)
+
+		encoded, err := utils.EncodeJPEG(resized, variantQuality)
+		if err != nil {
+			return err
+		}
+		variant, err := g.store(ctx, key, resized, "jpeg", encoded)
+		if err != nil {
+			return err
+		}
+		variants = append(variants, variant)
+
+		if g.webp == "" {
+			continue
+		}
+		encoded, err = utils.EncodeWebP(g.webp, resized, variantQuality)
+		if err != nil {
+			return err
+		}
+		variant, err = g.store(ctx, key, resized, "webp", encoded)
+		if err != nil {
+			return err
+		}
+		variants = append(variants, variant)
+	}
+	if len(variants) == 0 {
+		return nil
+	}
+
+	if err := g.DB.Create(&variants).Error; err != nil {
+		return err
+	}
+	log.Printf("Generated %d variants of %v", len(variants), key)
+	return nil
+}
+
+func (g *VariantGenerator) store(ctx context.Context, key string, img image.Image, format string, data []byte) (models.MediaVariant, error) {
+	variant := models.MediaVariant{
+		SourceKey: key,
+		Width:     img.Bounds().Dx(),
+		Height:    img.Bounds().Dy(),
+		Format:    format,
+		Key:       storage.VariantKey(key, img.Bounds().Dx(), format),
+	}
+	return variant, g.Storage.Put(ctx, variant.Key, "image/"+format, data)
+}
+
+// DeleteVariants removes the variants of an object from the bucket and the
+// database. It is called whenever the object itself is deleted.
+func DeleteVariants(ctx context.Context, db *gorm.DB, store storage.Storage, key string) error {
+	err := store.List(ctx, storage.VariantsPrefix+key+"/", func(object storage.ObjectInfo) error {
+		return store.Delete(ctx, object.Key)
+	})
+	if err != nil {
+		return err
+	}
+	return db.Unscoped().Where("source_key = ?", key).Delete(&models.MediaVariant{}).Error
+}